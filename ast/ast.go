@@ -12,13 +12,20 @@ type Visitor interface {
 	VisitVariable(vr Variable) interface{}
 	VisitAssignment(a Assignment) interface{}
 	VisitCall(c Call) interface{}
+	VisitGet(g Get) interface{}
+	VisitIndex(ix Index) interface{}
+	VisitListLiteral(l ListLiteral) interface{}
 
 	VisitExprStmt(e ExprStmt) interface{}
 	VisitIfStmt(stmt IfStmt) interface{}
 	VisitWhileStmt(stmt WhileStmt) interface{}
+	VisitForStmt(stmt ForStmt) interface{}
 	VisitFuncDecl(f FuncDecl) interface{}
 	VisitVarDecl(d VarDecl) interface{}
 	VisitReturn(d ReturnStmt) interface{}
+	VisitTryStmt(stmt TryStmt) interface{}
+	VisitThrowStmt(stmt ThrowStmt) interface{}
+	VisitImportStmt(stmt ImportStmt) interface{}
 	VisitBlock(b Block) interface{}
 }
 
@@ -82,25 +89,62 @@ func (vr Variable) Accept(v Visitor) interface{} {
 	return v.VisitVariable(vr)
 }
 
+// Postfix is the '++' or '--' token of an increment, ex: i++ is i = i + 1
 type Assignment struct {
 	Name lexer.Token
 	Value Expression
+	Postfix lexer.Token
 }
 
 func (a Assignment) Accept(v Visitor) interface{} {
 	return v.VisitAssignment(a)
 }
 
+// Keywords are the arguments passed by name, ex: f(x, scale: 2)
 type Call struct {
 	Callee Expression
 	Paren lexer.Token
 	Arguments []Expression
+	Keywords []KeywordArg
+}
+
+type KeywordArg struct {
+	Name lexer.Token
+	Value Expression
 }
 
 func (c Call) Accept(v Visitor) interface{} {
 	return v.VisitCall(c)
 }
 
+type Get struct {
+	Object Expression
+	Name lexer.Token
+}
+
+func (g Get) Accept(v Visitor) interface{} {
+	return v.VisitGet(g)
+}
+
+type Index struct {
+	Object Expression
+	Bracket lexer.Token
+	Index Expression
+}
+
+func (ix Index) Accept(v Visitor) interface{} {
+	return v.VisitIndex(ix)
+}
+
+type ListLiteral struct {
+	Bracket lexer.Token
+	Elements []Expression
+}
+
+func (l ListLiteral) Accept(v Visitor) interface{} {
+	return v.VisitListLiteral(l)
+}
+
 //Statement types:
 
 type Statement interface {
@@ -134,9 +178,26 @@ func (w WhileStmt) Accept(v Visitor) interface{} {
 	return v.VisitWhileStmt(w)
 }
 
+// ex: for let i = 0; i < 10; i++ then
+type ForStmt struct {
+	Keyword lexer.Token
+	Initializer Statement
+	Condition Expression
+	Increment Expression
+	LoopBranch Statement
+}
+
+func (f ForStmt) Accept(v Visitor) interface{} {
+	return v.VisitForStmt(f)
+}
+
+// Defaults holds the default value of each parameter, or nil if it has none.
+// If Rest is set, the last parameter collects the remaining arguments, ex: function sum: ...nums =
 type FuncDecl struct {
 	Name lexer.Token
 	Parameters []lexer.Token
+	Defaults []Expression
+	Rest bool
 	Block Block
 }
 
@@ -162,6 +223,39 @@ func (r ReturnStmt) Accept(v Visitor) interface{} {
 	return v.VisitReturn(r)
 }
 
+// CatchName and CatchBranch, or FinallyBranch, may be empty.
+type TryStmt struct {
+	Keyword lexer.Token
+	TryBranch Statement
+	CatchName lexer.Token
+	CatchBranch Statement
+	FinallyBranch Statement
+}
+
+func (t TryStmt) Accept(v Visitor) interface{} {
+	return v.VisitTryStmt(t)
+}
+
+type ThrowStmt struct {
+	Keyword lexer.Token
+	Value Expression
+}
+
+func (t ThrowStmt) Accept(v Visitor) interface{} {
+	return v.VisitThrowStmt(t)
+}
+
+// Name is empty unless the import has an 'as' name.
+type ImportStmt struct {
+	Keyword lexer.Token
+	Path lexer.Token
+	Name lexer.Token
+}
+
+func (i ImportStmt) Accept(v Visitor) interface{} {
+	return v.VisitImportStmt(i)
+}
+
 type Block struct {
 	Stmts []Statement
 }
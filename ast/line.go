@@ -0,0 +1,62 @@
+package ast
+
+import "friston/lexer"
+
+// Returns the source line a node starts on, or 0 if it has no tokens (ex: an empty block).
+func Line(node interface{}) int {
+	return Start(node).Line
+}
+
+// Returns the first token of a node (the condition of if and while statements), or an empty token if it has none.
+func Start(node interface{}) lexer.Token {
+	switch n := node.(type) {
+	case Binary:
+		return Start(n.X)
+	case Logic:
+		return Start(n.X)
+	case Unary:
+		return n.Op
+	case Group:
+		return n.Left
+	case Literal:
+		return n.X
+	case Variable:
+		return n.Name
+	case Assignment:
+		return n.Name
+	case Call:
+		return Start(n.Callee)
+	case Get:
+		return Start(n.Object)
+	case Index:
+		return Start(n.Object)
+	case ListLiteral:
+		return n.Bracket
+	case ExprStmt:
+		return Start(n.Expr)
+	case IfStmt:
+		return Start(n.Condition)
+	case WhileStmt:
+		return Start(n.Condition)
+	case ForStmt:
+		return n.Keyword
+	case FuncDecl:
+		return n.Name
+	case VarDecl:
+		return n.Name
+	case ReturnStmt:
+		return n.Keyword
+	case TryStmt:
+		return n.Keyword
+	case ThrowStmt:
+		return n.Keyword
+	case ImportStmt:
+		return n.Keyword
+	case Block:
+		if len(n.Stmts) > 0 {
+			return Start(n.Stmts[0])
+		}
+	}
+
+	return lexer.Token{}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"friston/errors"
+	"friston/lexer"
+	"friston/visitors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const commandHelp = `Commands:
+  :env            list the variables declared in this session, and their values
+  :ast <src>      print the syntax tree of src
+  :tokens <src>   print the tokens of src
+  :load <file>    run a file in this session
+  :reset          start a new session, dropping its variables
+  :time <src>     run src, and print how long it took
+  :help           show this help
+  exit            leave the REPL`
+
+// Runs a REPL command, ex: ":load lib.fn"
+func (s *session) command(line string) {
+	name, arg := line, ""
+	if space := strings.IndexByte(line, ' '); space >= 0 {
+		name, arg = line[:space], strings.TrimSpace(line[space+1:])
+	}
+
+	switch name {
+	case ":env":
+		s.env()
+	case ":ast":
+		if stmts, ok := parse(arg, true, os.Stderr); ok {
+			printer := visitors.ASTPrinter{}
+			for _, stmt := range stmts {
+				stmt.Accept(printer)
+			}
+			fmt.Println()
+		}
+	case ":tokens":
+		lex := lexer.NewLexer(arg, true)
+		lex.Errors = os.Stderr
+		if tokens, lexErr := lex.ScanTokens(); !lexErr {
+			lexer.PrintTokens(tokens)
+		}
+	case ":load":
+		s.load(arg)
+	case ":reset":
+		*s = *newSession()
+		fmt.Println("Session reset.")
+	case ":time":
+		start := time.Now()
+		s.run(arg)
+		fmt.Printf("Took %v.\n", time.Since(start))
+	case ":help":
+		fmt.Println(commandHelp)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command '%s', type :help for commands.\n", name)
+	}
+}
+
+// Lists the session's own global variables, sorted by name.
+func (s *session) env() {
+	for _, name := range s.inter.Globals() {
+		if s.builtins[name] {
+			continue
+		}
+		value, _ := s.inter.Lookup(name)
+		if str, ok := value.(string); ok {
+			value = fmt.Sprintf("%q", str)
+		}
+		fmt.Printf("%s = %v\n", name, value)
+	}
+}
+
+// Runs a file in the session's global scope, so its declarations can be used at the prompt.
+// Its errors are reported with the file's name and lines.
+func (s *session) load(path string) {
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "Usage: :load <file>")
+		return
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't load '%s': %v\n", path, err)
+		return
+	}
+
+	stmts, ok := parse(string(src), false, os.Stderr)
+	if !ok {
+		return
+	}
+
+	// The copy shares the session's globals, but not its script name or directory.
+	inter := s.inter
+	inter.Repl = false
+	inter.Script = filepath.Base(path)
+	inter.Dir = filepath.Dir(path)
+	if exit, ok := inter.Interpret(stmts).(errors.ExitError); ok {
+		os.Exit(exit.Code)
+	}
+}
@@ -0,0 +1,461 @@
+package dap
+
+import (
+	"fmt"
+	"friston/ast"
+	"friston/errors"
+	"friston/interpreter"
+	"friston/lexer"
+	"friston/parser"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// How the program carries on after a pause.
+type stepMode int
+
+const (
+	stepContinue stepMode = iota
+	stepInto
+	stepOver
+	stepOut
+)
+
+// A launched program, which runs on its own goroutine. It's the interpreter's Debugger, so it pauses the
+// goroutine before statements at breakpoints and after steps. While paused, the goroutine runs the requests
+// that inspect the program, so they don't race with it.
+type program struct {
+	s     *Server
+	path  string
+	stmts []ast.Statement
+	inter interpreter.Interpreter
+
+	// Guarded by s.mu.
+	mode           stepMode
+	depth          int
+	pauseRequested bool
+	paused         bool
+	terminated     bool
+	// The reason for the next stop after a step: "entry" before the program's first pause, then "step".
+	stepReason string
+
+	// Run on the program's goroutine while it's paused, where nil resumes it.
+	commands chan func(p *program)
+	done     chan struct{}
+
+	// Only used on the program's goroutine.
+	current   interpreter.Interpreter
+	lastFile  string
+	lastLine  int
+	lastDepth int
+	// Set while an expression is evaluated or the program is stopping, when statements don't pause.
+	busy bool
+	// Paths of the files in stack frames, by their names.
+	paths map[string]string
+	// Values with children, by their variablesReference minus one. References only last until the program resumes.
+	refs []interface{}
+}
+
+// Parses a program and creates its interpreter. Syntax errors are sent as output.
+func (s *Server) launch(args launchArguments) error {
+	if s.program != nil {
+		return fmt.Errorf("A program has already been launched.")
+	}
+
+	path, err := filepath.Abs(args.Program)
+	if err != nil {
+		return err
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Can't read '%s': %v", args.Program, err)
+	}
+
+	lex := lexer.NewLexer(string(src), false)
+	lex.Errors = output{s, "stderr"}
+	tokens, lexErr := lex.ScanTokens()
+	var stmts []ast.Statement
+	parErr := false
+	if !lexErr {
+		par := parser.NewParser(tokens)
+		par.Errors = output{s, "stderr"}
+		stmts, parErr = par.Parse()
+	}
+	if lexErr || parErr {
+		return fmt.Errorf("Could not parse '%s'.", args.Program)
+	}
+
+	// Stdin may be the client's connection, so programs have no input.
+	inter := interpreter.NewInterpreter(false, output{s, "stdout"}, output{s, "stderr"}, strings.NewReader(""), interpreter.AllCapabilities)
+	inter.SearchPath = s.SearchPath
+	inter.Script = filepath.Base(path)
+	inter.Dir = filepath.Dir(path)
+	list := &interpreter.List{}
+	for _, arg := range args.Args {
+		list.Elements = append(list.Elements, arg)
+	}
+	inter.Define("args", list)
+
+	p := &program{
+		s:          s,
+		path:       path,
+		stmts:      stmts,
+		inter:      inter,
+		stepReason: "entry",
+		commands:   make(chan func(p *program)),
+		done:       make(chan struct{}),
+		paths:      map[string]string{inter.Script: path},
+	}
+	if args.StopOnEntry {
+		p.mode = stepInto
+	}
+	if !args.NoDebug {
+		p.inter.Debugger = p
+	}
+
+	s.program = p
+	if s.configured {
+		p.start()
+	}
+	return nil
+}
+
+// Runs the program on its own goroutine, sending the exited and terminated events when it ends.
+func (p *program) start() {
+	go func() {
+		code := 0
+		err := p.inter.Interpret(p.stmts)
+		if exit, ok := err.(errors.ExitError); ok {
+			code = exit.Code
+		} else if err != nil {
+			// The friston command's exit code for runtime errors.
+			code = 70
+		}
+
+		p.s.event("exited", exitedEvent{code})
+		p.s.event("terminated", nil)
+	}()
+}
+
+func (p *program) Before(i interpreter.Interpreter, stmt ast.Statement) {
+	if p.busy {
+		return
+	}
+
+	line, depth := ast.Line(stmt), i.CallDepth()
+	path := p.sourcePath(i, i.StackTrace(line)[0].File)
+	newLine := path != p.lastFile || line != p.lastLine || depth != p.lastDepth
+	p.lastFile, p.lastLine, p.lastDepth = path, line, depth
+
+	p.s.mu.Lock()
+	if p.terminated {
+		p.s.mu.Unlock()
+		p.stop()
+	}
+	reason := ""
+	switch {
+	case p.pauseRequested:
+		reason = "pause"
+	case !newLine:
+		// A line with several statements (ex: if x then y()) only pauses once.
+	case p.s.breakpoints[path][line]:
+		reason = "breakpoint"
+	case p.mode == stepInto, p.mode == stepOver && depth <= p.depth, p.mode == stepOut && depth < p.depth:
+		reason = p.stepReason
+	}
+	if reason != "" {
+		p.paused = true
+		p.pauseRequested = false
+		p.stepReason = "step"
+	}
+	p.s.mu.Unlock()
+
+	if reason != "" {
+		p.pause(i, reason)
+	}
+}
+
+// Sends the stopped event, then runs commands until one resumes the program.
+func (p *program) pause(i interpreter.Interpreter, reason string) {
+	p.current = i
+	p.refs = nil
+	p.s.event("stopped", stoppedEvent{reason, threadID, true})
+
+	for command := range p.commands {
+		if command == nil {
+			break
+		}
+		command(p)
+		p.done <- struct{}{}
+	}
+
+	p.s.mu.Lock()
+	terminated := p.terminated
+	p.s.mu.Unlock()
+	if terminated {
+		p.stop()
+	}
+}
+
+// Exits the program from its goroutine. Finally branches still run as it exits, but don't pause.
+func (p *program) stop() {
+	p.busy = true
+	panic(errors.ExitError{Code: 0})
+}
+
+// Returns the absolute path of a file in a stack frame, which is the program or a module it imports.
+func (p *program) sourcePath(i interpreter.Interpreter, file string) string {
+	if path, ok := p.paths[file]; ok {
+		return path
+	}
+	if path, ok := i.ModulePath(file); ok {
+		p.paths[file] = path
+		return path
+	}
+	return file
+}
+
+// The server's side of the program, called by requests.
+
+// Sets how a paused program steps when it's woken. Returns false if it isn't paused.
+func (p *program) resume(mode stepMode) bool {
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+	if !p.paused {
+		return false
+	}
+	p.mode = mode
+	p.depth = p.current.CallDepth()
+	p.paused = false
+	return true
+}
+
+// Wakes the program after resume, once the response has been sent, so it can't stop again before it.
+func (p *program) wake() {
+	p.commands <- nil
+}
+
+// Runs a command on the program's goroutine while it's paused. Returns false if it isn't.
+func (p *program) inspect(command func(p *program)) bool {
+	p.s.mu.Lock()
+	paused := p.paused
+	p.s.mu.Unlock()
+	if !paused {
+		return false
+	}
+
+	p.commands <- command
+	<-p.done
+	return true
+}
+
+// Pauses the program before its next statement.
+func (p *program) requestPause() {
+	p.s.mu.Lock()
+	p.pauseRequested = true
+	p.s.mu.Unlock()
+}
+
+// Stops the program before its next statement, or now if it's paused.
+func (p *program) terminate() {
+	p.s.mu.Lock()
+	p.terminated = true
+	paused := p.paused
+	p.paused = false
+	p.s.mu.Unlock()
+
+	if paused {
+		p.wake()
+	}
+}
+
+// Inspecting a paused program, on its goroutine. Frame IDs are the frame's index plus one, from the innermost out.
+
+func (p *program) stackTrace() []StackFrame {
+	frames := []StackFrame{}
+	for n, frame := range p.current.StackTrace(p.lastLine) {
+		path := p.sourcePath(p.current, frame.File)
+		frames = append(frames, StackFrame{n + 1, frame.Function, &Source{filepath.Base(path), path}, frame.Line, 1})
+	}
+	return frames
+}
+
+// Returns a frame's scopes, from its locals out to the globals. Natives and constants are left out of the
+// globals, and modules have an extra scope of them, which is left out too.
+func (p *program) scopes(frameID int) []Scope {
+	values := p.current.Frame(frameID - 1).Scopes()
+	last := len(values) - 1
+	values[last] = userValues(values[last])
+	if len(values[last]) == 0 && last > 0 {
+		values = values[:last]
+		last--
+	}
+
+	scopes := []Scope{}
+	for n, scope := range values {
+		name := "Closure"
+		switch n {
+		case last:
+			name = "Globals"
+		case 0:
+			name = "Locals"
+		}
+		scopes = append(scopes, Scope{name, p.reference(scope), n == last})
+	}
+	return scopes
+}
+
+// Returns a copy of a scope without its natives and constants.
+func userValues(scope map[string]interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	for name, value := range scope {
+		_, native := interpreter.Natives[name]
+		_, constant := interpreter.Constants[name]
+		if !native && !constant {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// Returns the children of a scope, list or map.
+func (p *program) variables(reference int) []Variable {
+	variables := []Variable{}
+	if reference < 1 || reference > len(p.refs) {
+		return variables
+	}
+
+	switch value := p.refs[reference-1].(type) {
+	case map[string]interface{}:
+		for _, name := range sortedKeys(value) {
+			variables = append(variables, p.variable(name, value[name]))
+		}
+	case *interpreter.List:
+		for n, element := range value.Elements {
+			variables = append(variables, p.variable("["+strconv.Itoa(n)+"]", element))
+		}
+	case *interpreter.Map:
+		for _, key := range sortedKeys(value.Entries) {
+			variables = append(variables, p.variable(strconv.Quote(key), value.Entries[key]))
+		}
+	}
+	return variables
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (p *program) variable(name string, value interface{}) Variable {
+	v := Variable{Name: name, Value: format(value), Type: typeName(value)}
+	switch value := value.(type) {
+	case *interpreter.List:
+		if len(value.Elements) > 0 {
+			v.VariablesReference = p.reference(value)
+		}
+	case *interpreter.Map:
+		if len(value.Entries) > 0 {
+			v.VariablesReference = p.reference(value)
+		}
+	}
+	return v
+}
+
+func (p *program) reference(value interface{}) int {
+	p.refs = append(p.refs, value)
+	return len(p.refs)
+}
+
+// Strings are quoted, so they can be told apart from other values.
+func format(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(value)
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case *interpreter.List:
+		return "list"
+	case *interpreter.Map:
+		return "map"
+	case interpreter.Function:
+		return "function"
+	}
+	return ""
+}
+
+// Evaluates an expression in a frame's scope. Functions it calls don't pause.
+func (p *program) evaluate(src string, frameID int) (Variable, error) {
+	lex := lexer.NewLexer(src, false)
+	lex.Errors = ioutil.Discard
+	tokens, lexErr := lex.ScanTokens()
+	var stmts []ast.Statement
+	parErr := true
+	if !lexErr {
+		par := parser.NewParser(tokens)
+		par.Errors = ioutil.Discard
+		stmts, parErr = par.Parse()
+		if parErr {
+			return Variable{}, fmt.Errorf("%s", par.SyntaxErrors[0].Message)
+		}
+	}
+	if lexErr {
+		return Variable{}, fmt.Errorf("%s", lex.SyntaxErrors[0].Message)
+	}
+
+	var expr ast.ExprStmt
+	ok := len(stmts) == 1
+	if ok {
+		expr, ok = stmts[0].(ast.ExprStmt)
+	}
+	if !ok {
+		return Variable{}, fmt.Errorf("Only expressions can be evaluated.")
+	}
+
+	if frameID < 1 {
+		frameID = 1
+	}
+	p.busy = true
+	value, err := p.current.Frame(frameID - 1).Eval(expr.Expr)
+	p.busy = false
+
+	if runtimeErr, ok := err.(errors.RuntimeError); ok {
+		return Variable{}, fmt.Errorf("%s", runtimeErr.Message)
+	} else if err != nil {
+		// exit() in an expression exits the program, once it resumes.
+		p.s.mu.Lock()
+		p.terminated = true
+		p.s.mu.Unlock()
+		return Variable{}, fmt.Errorf("The program is exiting.")
+	}
+	return p.variable("", value), nil
+}
+
+// Sends what the program writes to an output category as output events.
+type output struct {
+	s        *Server
+	category string
+}
+
+func (o output) Write(b []byte) (int, error) {
+	o.s.event("output", outputEvent{o.category, string(b)})
+	return len(b), nil
+}
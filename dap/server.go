@@ -0,0 +1,279 @@
+// Package dap is a Debug Adapter Protocol server for friston, which lets editors debug programs over stdio or TCP.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// The program runs as the only thread.
+const threadID = 1
+
+// Debugs one program for one client.
+type Server struct {
+	// Directories imports are resolved from, like the interpreter's SearchPath.
+	SearchPath []string
+
+	in  *bufio.Reader
+	out io.Writer
+	// Guards out and seq, as the program's output and stops are sent from its own goroutine.
+	writing sync.Mutex
+	seq     int
+
+	// Guards breakpoints, and the program's state that requests change while it runs.
+	mu sync.Mutex
+	// Lines with breakpoints, by the absolute path of their file.
+	breakpoints map[string]map[int]bool
+
+	program *program
+	// Set by configurationDone, after the client has set the breakpoints. The program starts once it's
+	// launched and configured.
+	configured bool
+	// Set when a request resumes the program, which is woken after the response.
+	resumed bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, breakpoints: map[string]map[int]bool{}}
+}
+
+// Handles requests until the client disconnects or the input ends, stopping the program if it's still running.
+func (s *Server) Serve() error {
+	defer func() {
+		if s.program != nil {
+			s.program.terminate()
+		}
+	}()
+
+	for {
+		body, err := s.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("bad message: %v", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		s.handle(req)
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+// Reads a message's body, which follows a Content-Length header.
+func (s *Server) read() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("bad Content-Length: %v", err)
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(s.in, body)
+	return body, err
+}
+
+// Sends a message, filling in its seq.
+func (s *Server) write(message func(seq int) interface{}) {
+	s.writing.Lock()
+	defer s.writing.Unlock()
+
+	s.seq++
+	body, err := json.Marshal(message(s.seq))
+	if err != nil {
+		panic(err)
+	}
+	// Write errors are ignored, as the client may disconnect while the program is still printing.
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *Server) respond(req request, body interface{}, err error) {
+	s.write(func(seq int) interface{} {
+		res := response{Seq: seq, Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+		if err != nil {
+			res.Message, res.Body = err.Error(), nil
+		}
+		return res
+	})
+}
+
+func (s *Server) event(name string, body interface{}) {
+	s.write(func(seq int) interface{} {
+		return event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+// Runs a request's handler and responds with its result.
+func (s *Server) handle(req request) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.respond(req, nil, fmt.Errorf("%v", r))
+		}
+	}()
+
+	body, err := s.dispatch(req)
+	s.respond(req, body, err)
+	if s.resumed {
+		s.resumed = false
+		s.program.wake()
+	}
+
+	// The client sends breakpoints and configurationDone once it's told the adapter is initialized.
+	if req.Command == "initialize" {
+		s.event("initialized", nil)
+	}
+}
+
+func (s *Server) dispatch(req request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		return map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		}, nil
+	case "launch":
+		var args launchArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args)
+	case "setBreakpoints":
+		var args setBreakpointsArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"breakpoints": s.setBreakpoints(args)}, nil
+	case "configurationDone":
+		s.configured = true
+		if s.program != nil {
+			s.program.start()
+		}
+		return nil, nil
+	case "disconnect", "terminate":
+		if s.program != nil {
+			s.program.terminate()
+		}
+		return nil, nil
+
+	case "threads":
+		return map[string]interface{}{"threads": []Thread{{threadID, "main"}}}, nil
+	case "continue":
+		return map[string]interface{}{"allThreadsContinued": true}, s.resume(stepContinue)
+	case "next":
+		return nil, s.resume(stepOver)
+	case "stepIn":
+		return nil, s.resume(stepInto)
+	case "stepOut":
+		return nil, s.resume(stepOut)
+	case "pause":
+		if s.program == nil {
+			return nil, fmt.Errorf("No program is running.")
+		}
+		s.program.requestPause()
+		return nil, nil
+
+	case "stackTrace":
+		var frames []StackFrame
+		err := s.inspect(func(p *program) {
+			frames = p.stackTrace()
+		})
+		return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, err
+	case "scopes":
+		var args frameArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		var scopes []Scope
+		err := s.inspect(func(p *program) {
+			scopes = p.scopes(args.FrameID)
+		})
+		return map[string]interface{}{"scopes": scopes}, err
+	case "variables":
+		var args variablesArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		var variables []Variable
+		err := s.inspect(func(p *program) {
+			variables = p.variables(args.VariablesReference)
+		})
+		return map[string]interface{}{"variables": variables}, err
+	case "evaluate":
+		var args evaluateArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		var result Variable
+		var evalErr error
+		err := s.inspect(func(p *program) {
+			result, evalErr = p.evaluate(args.Expression, args.FrameID)
+		})
+		if err == nil {
+			err = evalErr
+		}
+		return map[string]interface{}{"result": result.Value, "variablesReference": result.VariablesReference}, err
+	}
+
+	return nil, fmt.Errorf("Unknown command '%s'.", req.Command)
+}
+
+func decode(req request, args interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	return json.Unmarshal(req.Arguments, args)
+}
+
+// Replaces the breakpoints of a file. Lines aren't checked for statements, so every breakpoint is verified.
+func (s *Server) setBreakpoints(args setBreakpointsArguments) []Breakpoint {
+	path, _ := filepath.Abs(args.Source.Path)
+	lines := map[int]bool{}
+	breakpoints := []Breakpoint{}
+	for _, bp := range args.Breakpoints {
+		lines[bp.Line] = true
+		breakpoints = append(breakpoints, Breakpoint{true, bp.Line, args.Source})
+	}
+
+	s.mu.Lock()
+	s.breakpoints[path] = lines
+	s.mu.Unlock()
+	return breakpoints
+}
+
+func (s *Server) resume(mode stepMode) error {
+	if s.program == nil || !s.program.resume(mode) {
+		return fmt.Errorf("The program isn't paused.")
+	}
+	s.resumed = true
+	return nil
+}
+
+// Runs f while the program is paused, or returns an error if it isn't.
+func (s *Server) inspect(f func(p *program)) error {
+	if s.program == nil || !s.program.inspect(f) {
+		return fmt.Errorf("The program isn't paused.")
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// A client of a server running on its own goroutine, which reads the server's messages into a channel.
+type client struct {
+	t        *testing.T
+	in       *io.PipeWriter
+	seq      int
+	messages chan map[string]interface{}
+	done     chan error
+}
+
+func newClient(t *testing.T) *client {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	c := &client{t: t, in: inWriter, messages: make(chan map[string]interface{}, 100), done: make(chan error, 1)}
+
+	go func() {
+		c.done <- NewServer(inReader, outWriter).Serve()
+		outWriter.Close()
+	}()
+	go func() {
+		defer close(c.messages)
+		reader := bufio.NewReader(outReader)
+		for {
+			header, err := textproto.NewReader(reader).ReadMIMEHeader()
+			if err != nil {
+				return
+			}
+			length, _ := strconv.Atoi(header.Get("Content-Length"))
+			body := make([]byte, length)
+			if _, err := io.ReadFull(reader, body); err != nil {
+				return
+			}
+			var message map[string]interface{}
+			if json.Unmarshal(body, &message) == nil {
+				c.messages <- message
+			}
+		}
+	}()
+	return c
+}
+
+func (c *client) send(command string, args interface{}) int {
+	c.seq++
+	body, _ := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return c.seq
+}
+
+// Waits for the first message that matches, skipping the others.
+func (c *client) until(what string, match func(message map[string]interface{}) bool) map[string]interface{} {
+	c.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case message, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("the server stopped before %s", what)
+			}
+			if match(message) {
+				return message
+			}
+		case <-timeout:
+			c.t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// Sends a request, and returns the body of its response, which must be successful.
+func (c *client) request(command string, args interface{}) map[string]interface{} {
+	c.t.Helper()
+	seq := c.send(command, args)
+	res := c.until("the response to "+command, func(m map[string]interface{}) bool {
+		return m["type"] == "response" && m["request_seq"] == float64(seq)
+	})
+	if res["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, res["message"])
+	}
+	body, _ := res["body"].(map[string]interface{})
+	return body
+}
+
+func (c *client) event(name string) map[string]interface{} {
+	c.t.Helper()
+	message := c.until("the "+name+" event", func(m map[string]interface{}) bool {
+		return m["type"] == "event" && m["event"] == name
+	})
+	body, _ := message["body"].(map[string]interface{})
+	return body
+}
+
+// Returns the variables of a scope or value by name.
+func (c *client) variables(reference interface{}) map[string]string {
+	c.t.Helper()
+	values := map[string]string{}
+	body := c.request("variables", map[string]interface{}{"variablesReference": reference})
+	for _, v := range body["variables"].([]interface{}) {
+		variable := v.(map[string]interface{})
+		values[variable["name"].(string)] = variable["value"].(string)
+	}
+	return values
+}
+
+// Writes a program to a new directory, which the caller removes.
+func writeProgram(t *testing.T, src string) string {
+	dir, err := ioutil.TempDir("", "dap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "main.fn")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBreakpoints(t *testing.T) {
+	path := writeProgram(t, "function add: a, b =\n"+
+		"    let sum = a + b\n"+
+		"    return sum\n"+
+		"let total = add(1, 2)\n"+
+		"println(total)\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	c := newClient(t)
+	c.request("initialize", map[string]interface{}{"adapterID": "friston"})
+	c.event("initialized")
+	c.request("launch", map[string]interface{}{"program": path})
+	body := c.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": path},
+		"breakpoints": []interface{}{map[string]interface{}{"line": 3}},
+	})
+	if breakpoints := body["breakpoints"].([]interface{}); len(breakpoints) != 1 {
+		t.Fatalf("breakpoints = %v", breakpoints)
+	}
+	c.request("configurationDone", nil)
+
+	if stopped := c.event("stopped"); stopped["reason"] != "breakpoint" {
+		t.Fatalf("stopped = %v, want a breakpoint", stopped)
+	}
+
+	frames := c.request("stackTrace", map[string]interface{}{"threadId": threadID})["stackFrames"].([]interface{})
+	if len(frames) != 2 {
+		t.Fatalf("stackFrames = %v, want add and the script", frames)
+	}
+	top := frames[0].(map[string]interface{})
+	if top["name"] != "add" || top["line"] != float64(3) {
+		t.Fatalf("top frame = %v, want add on line 3", top)
+	}
+
+	scopes := c.request("scopes", map[string]interface{}{"frameId": top["id"]})["scopes"].([]interface{})
+	locals := scopes[0].(map[string]interface{})
+	if locals["name"] != "Locals" {
+		t.Fatalf("first scope = %v, want Locals", locals)
+	}
+	variables := c.variables(locals["variablesReference"])
+	if variables["a"] != "1" || variables["b"] != "2" || variables["sum"] != "3" {
+		t.Fatalf("locals = %v", variables)
+	}
+
+	c.request("continue", map[string]interface{}{"threadId": threadID})
+	output := c.event("output")
+	if output["category"] != "stdout" || output["output"] != "3\n" {
+		t.Fatalf("output = %v", output)
+	}
+	if exited := c.event("exited"); exited["exitCode"] != float64(0) {
+		t.Fatalf("exited = %v", exited)
+	}
+	c.event("terminated")
+
+	c.request("disconnect", nil)
+	if err := <-c.done; err != nil {
+		t.Fatalf("Serve() = %v", err)
+	}
+}
+
+func TestStopOnEntry(t *testing.T) {
+	path := writeProgram(t, "let x = [1, 2]\nprintln(x)\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	c := newClient(t)
+	c.request("initialize", nil)
+	c.request("launch", map[string]interface{}{"program": path, "stopOnEntry": true})
+	c.request("configurationDone", nil)
+	if stopped := c.event("stopped"); stopped["reason"] != "entry" {
+		t.Fatalf("stopped = %v, want entry", stopped)
+	}
+
+	c.request("next", map[string]interface{}{"threadId": threadID})
+	if stopped := c.event("stopped"); stopped["reason"] != "step" {
+		t.Fatalf("stopped = %v, want a step", stopped)
+	}
+	result := c.request("evaluate", map[string]interface{}{"expression": "x", "frameId": 1})
+	if result["result"] != "[1, 2]" || result["variablesReference"] == float64(0) {
+		t.Fatalf("evaluate = %v", result)
+	}
+	if elements := c.variables(result["variablesReference"]); elements["[0]"] != "1" || elements["[1]"] != "2" {
+		t.Fatalf("elements = %v", elements)
+	}
+
+	c.request("continue", map[string]interface{}{"threadId": threadID})
+	c.event("terminated")
+	c.request("disconnect", nil)
+}
+
+func TestLaunchErrors(t *testing.T) {
+	c := newClient(t)
+	c.request("initialize", nil)
+
+	seq := c.send("launch", map[string]interface{}{"program": filepath.Join(os.TempDir(), "missing.fn")})
+	res := c.until("the response to launch", func(m map[string]interface{}) bool {
+		return m["type"] == "response" && m["request_seq"] == float64(seq)
+	})
+	if res["success"] != false {
+		t.Fatalf("launch of a missing file = %v, want a failure", res)
+	}
+
+	seq = c.send("continue", map[string]interface{}{"threadId": threadID})
+	res = c.until("the response to continue", func(m map[string]interface{}) bool {
+		return m["type"] == "response" && m["request_seq"] == float64(seq)
+	})
+	if res["success"] != false {
+		t.Fatalf("continue without a program = %v, want a failure", res)
+	}
+	c.request("disconnect", nil)
+}
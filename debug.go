@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"friston/ast"
+	"friston/dap"
+	"friston/errors"
+	"friston/interpreter"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const debugHelp = `Commands:
+  s, step              run to the next statement, stepping into calls
+  n, next              run to the next statement in this function, stepping over calls
+  o, out               run until the current function returns
+  c, continue          run until a breakpoint
+  b, break [lines...]  set breakpoints at lines of the program, or list them
+  d, delete <lines...> remove breakpoints
+  bt, stack            show the call stack
+  l, locals            show the variables in each scope, from the innermost out
+  p, print <expr>      evaluate an expression in the current scope
+  list                 show the source around the current line
+  q, quit              stop the program
+  h, help              show this help
+An empty line repeats the last step command.`
+
+// How the debugger carries on after a pause.
+type stepMode int
+
+const (
+	stepInto stepMode = iota
+	stepOver
+	stepOut
+	stepContinue
+)
+
+// An interactive debugger, which reads commands from in when the program pauses. Programs pause before their
+// first statement, at breakpoints, and after steps.
+type debugger struct {
+	script      string
+	lines       []string
+	in          *bufio.Reader
+	out         io.Writer
+	breakpoints map[int]bool
+	mode        stepMode
+	// The call depth when the last step started.
+	depth int
+	// Where the last statement was, so a line with several statements (ex: if x then y()) only pauses once.
+	lastFile  string
+	lastLine  int
+	lastDepth int
+	// Set while an expression is evaluated or the program is quitting, when statements don't pause.
+	busy        bool
+	lastCommand string
+}
+
+// Runs a program under the debugger.
+func debugFile(path string, args []string) int {
+	if path == "-" {
+		return usageError("debug reads its commands from stdin, so it needs a file.")
+	}
+	src, code := readProgram(path)
+	if code != exitOK {
+		return code
+	}
+	stmts, ok := parse(src, false, os.Stderr)
+	if !ok {
+		return exitSyntaxError
+	}
+
+	inter := newInterpreter(false, args)
+	inter.Script = filepath.Base(path)
+	inter.Dir = filepath.Dir(path)
+	inter.Debugger = &debugger{
+		script:      inter.Script,
+		lines:       strings.Split(src, "\n"),
+		in:          stdin,
+		out:         os.Stdout,
+		breakpoints: map[int]bool{},
+	}
+
+	fmt.Printf("Debugging %s, type h for help.\n", path)
+	err := inter.Interpret(stmts)
+	if exit, ok := err.(errors.ExitError); ok {
+		return exit.Code
+	} else if err != nil {
+		return exitRuntimeError
+	}
+	fmt.Println("Program finished.")
+	return exitOK
+}
+
+func (d *debugger) Before(i interpreter.Interpreter, stmt ast.Statement) {
+	if d.busy {
+		return
+	}
+
+	line, depth := ast.Line(stmt), i.CallDepth()
+	file := i.StackTrace(line)[0].File
+	if file == d.lastFile && line == d.lastLine && depth == d.lastDepth {
+		return
+	}
+	d.lastFile, d.lastLine, d.lastDepth = file, line, depth
+
+	pause := false
+	switch d.mode {
+	case stepInto:
+		pause = true
+	case stepOver:
+		pause = depth <= d.depth
+	case stepOut:
+		pause = depth < d.depth
+	}
+	if file == d.script && d.breakpoints[line] {
+		pause = true
+	}
+
+	if pause {
+		d.pause(i, file, line)
+	}
+}
+
+// Reads commands until one carries on running the program.
+func (d *debugger) pause(i interpreter.Interpreter, file string, line int) {
+	fmt.Fprintf(d.out, "%s:%d in %s\n", file, line, i.StackTrace(line)[0].Function)
+	if file == d.script {
+		d.listLine(line, line)
+	}
+
+	for {
+		fmt.Fprint(d.out, "(debug) ")
+		input, err := d.in.ReadString('\n')
+		if err != nil && input == "" {
+			// Without any more commands, the program runs to the end.
+			fmt.Fprintln(d.out)
+			d.mode = stepContinue
+			d.breakpoints = map[int]bool{}
+			return
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			input = d.lastCommand
+		}
+		command, arg := input, ""
+		if n := strings.IndexByte(input, ' '); n >= 0 {
+			command, arg = input[:n], strings.TrimSpace(input[n+1:])
+		}
+
+		switch command {
+		case "":
+		case "s", "step", "n", "next", "o", "out", "c", "continue":
+			d.mode = map[string]stepMode{
+				"s": stepInto, "step": stepInto, "n": stepOver, "next": stepOver,
+				"o": stepOut, "out": stepOut, "c": stepContinue, "continue": stepContinue,
+			}[command]
+			d.depth = i.CallDepth()
+			d.lastCommand = command
+			return
+		case "b", "break":
+			d.setBreakpoints(arg, true)
+		case "d", "delete":
+			d.setBreakpoints(arg, false)
+		case "bt", "stack":
+			for _, frame := range i.StackTrace(line) {
+				fmt.Fprintf(d.out, "  %s\n", frame)
+			}
+		case "l", "locals":
+			d.printScopes(i)
+		case "p", "print":
+			d.print(i, arg)
+		case "list":
+			d.listLine(line-5, line+5)
+		case "q", "quit":
+			// Finally branches still run as the program exits, but don't pause.
+			d.busy = true
+			panic(errors.ExitError{Code: exitOK})
+		case "h", "help":
+			fmt.Fprintln(d.out, debugHelp)
+		default:
+			fmt.Fprintf(d.out, "Unknown command '%s', type h for help.\n", command)
+		}
+	}
+}
+
+// Sets or removes breakpoints at the given lines, or lists them if there are none.
+func (d *debugger) setBreakpoints(arg string, set bool) {
+	if arg == "" {
+		var lines []int
+		for line := range d.breakpoints {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		if len(lines) == 0 {
+			fmt.Fprintln(d.out, "No breakpoints.")
+		}
+		for _, line := range lines {
+			d.listLine(line, line)
+		}
+		return
+	}
+
+	for _, field := range strings.Fields(arg) {
+		line, err := strconv.Atoi(field)
+		if err != nil || line < 1 || line > len(d.lines) {
+			fmt.Fprintf(d.out, "'%s' isn't a line of %s.\n", field, d.script)
+			continue
+		}
+		if set {
+			d.breakpoints[line] = true
+		} else {
+			delete(d.breakpoints, line)
+		}
+	}
+}
+
+// Prints lines of the program, marking the current line with '>' and breakpoints with '*'.
+func (d *debugger) listLine(from int, to int) {
+	for line := from; line <= to; line++ {
+		if line < 1 || line > len(d.lines) {
+			continue
+		}
+
+		marker := " "
+		if d.breakpoints[line] {
+			marker = "*"
+		}
+		if line == d.lastLine && d.lastFile == d.script {
+			marker += ">"
+		} else {
+			marker += " "
+		}
+		fmt.Fprintf(d.out, "%s %4d  %s\n", marker, line, d.lines[line-1])
+	}
+}
+
+// Prints each scope's variables, leaving natives and constants out of the globals.
+func (d *debugger) printScopes(i interpreter.Interpreter) {
+	scopes := i.Scopes()
+	for n, scope := range scopes {
+		var names []string
+		for name := range scope {
+			_, native := interpreter.Natives[name]
+			_, constant := interpreter.Constants[name]
+			if n < len(scopes)-1 || !native && !constant {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		if n == len(scopes)-1 {
+			fmt.Fprintln(d.out, "globals:")
+		} else {
+			fmt.Fprintf(d.out, "scope %d:\n", n)
+		}
+		for _, name := range names {
+			fmt.Fprintf(d.out, "  %s = %s\n", name, debugValue(scope[name]))
+		}
+	}
+}
+
+// Evaluates an expression in the paused scope. Statements it calls don't pause.
+func (d *debugger) print(i interpreter.Interpreter, src string) {
+	stmts, ok := parse(src, false, d.out)
+	if !ok {
+		return
+	}
+	if len(stmts) != 1 {
+		fmt.Fprintln(d.out, "print needs an expression.")
+		return
+	}
+	expr, ok := stmts[0].(ast.ExprStmt)
+	if !ok {
+		fmt.Fprintln(d.out, "print needs an expression.")
+		return
+	}
+
+	d.busy = true
+	value, err := i.Eval(expr.Expr)
+	d.busy = false
+
+	if runtimeErr, ok := err.(errors.RuntimeError); ok {
+		fmt.Fprintf(d.out, "Error: %s\n", runtimeErr.Message)
+	} else if err != nil {
+		panic(err)
+	} else {
+		fmt.Fprintln(d.out, debugValue(value))
+	}
+}
+
+// Strings are quoted, so they can be told apart from other values.
+func debugValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(value)
+}
+
+// Serves the Debug Adapter Protocol over stdio, or to each client that connects to an address, one at a time.
+func serveDAP(args []string) int {
+	flags := flag.NewFlagSet("dap", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	listen := flags.String("listen", "", "")
+	if err := flags.Parse(args); err != nil {
+		return usageError("dap: " + err.Error() + ".")
+	}
+	if flags.NArg() > 0 {
+		return usageError("dap doesn't take files, its client launches them.")
+	}
+
+	if *listen == "" {
+		server := dap.NewServer(os.Stdin, os.Stdout)
+		server.SearchPath = searchPath()
+		if err := server.Serve(); err != nil {
+			fmt.Fprintf(os.Stderr, "dap: %v\n", err)
+			return 1
+		}
+		return exitOK
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dap: %v\n", err)
+		return 1
+	}
+	// The address is printed, as a port of 0 picks a free one.
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "dap: %v\n", err)
+			return 1
+		}
+
+		server := dap.NewServer(conn, conn)
+		server.SearchPath = searchPath()
+		if err := server.Serve(); err != nil {
+			fmt.Fprintf(os.Stderr, "dap: %v\n", err)
+		}
+		conn.Close()
+	}
+}
@@ -1,8 +1,6 @@
 package environment
 
 import (
-	"fmt"
-	"friston/errors"
 	"friston/lexer"
 )
 
@@ -36,10 +34,11 @@ func (e *Environment) AddParent(parentEnv Environment) {
 	e.parent = &parentEnv
 }
 
-func (e *Environment) Get(name lexer.Token) interface{} {
+// Get the value of a variable, and whether it is declared in this or a parent scope.
+func (e *Environment) Get(name lexer.Token) (interface{}, bool) {
 	value, ok := e.Values[name.Lexeme]
 	if ok {
-		return value
+		return value, true
 	}
 
 	// Recursively check parents for variable if it's not found in current scope.
@@ -47,29 +46,25 @@ func (e *Environment) Get(name lexer.Token) interface{} {
 		return e.parent.Get(name)
 	}
 
-	// TODO: Make this a runtime error
-	errors.ThrowError(name.Line, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme))
-	return nil
+	return nil, false
 }
 
 // Assign value to a variable in current scope, or parent scopes, if it exists.
-func (e *Environment) Assign(name lexer.Token, value interface{}) {
+// Returns false if the variable is undeclared.
+func (e *Environment) Assign(name lexer.Token, value interface{}) bool {
 	_, ok := e.Values[name.Lexeme]
 	if ok {
 		e.Values[name.Lexeme] = value
-		return
+		return true
 	}
 
 	if e.parent != nil {
 		// Just like Get(), recurively check parent scopes for the target variable.
-		e.parent.Assign(name, value)
-		return
+		return e.parent.Assign(name, value)
 	}
 
-	// TODO: Stop execution with runtime error.
-	// TODO: Fix line number.
 	// TODO: Convert name back to token, not string, in all uses.
-	errors.ThrowError(0, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme))
+	return false
 }
 
 // Declare a new variable in the current scope
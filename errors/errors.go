@@ -1,20 +1,95 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
 
 // TODO: Report column of error, print line of error, etc.
-// TODO: Differentiate between syntax and runtime errors, stop execution with runtime errors, etc.
 
-// Prints error message and sets error flag
-func ThrowError(line int, message string) {
-	report(line, "Error: " + message)
+// A single function call on the friston call stack, used to build stack traces.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (f Frame) String() string {
+	// The REPL doesn't track line numbers.
+	if f.Line == 0 {
+		return fmt.Sprintf("at %s (%s)", f.Function, f.File)
+	}
+	return fmt.Sprintf("at %s (%s:%d)", f.Function, f.File, f.Line)
+}
+
+// RuntimeError stops execution of a running program. It is raised by the interpreter with panic().
+// Value holds the friston value given to a throw statement, if any.
+type RuntimeError struct {
+	Line    int
+	Message string
+	Trace   []Frame
+	Value   interface{}
+}
+
+func (e RuntimeError) Error() string {
+	return e.Message
+}
+
+// AbortError stops a running program from outside of it (ex: a timeout), and can't be caught by a try statement.
+// Cause is the reason it was stopped, ex: context.Canceled.
+type AbortError struct {
+	RuntimeError
+	Cause error
+}
+
+func (e AbortError) Unwrap() error {
+	return e.Cause
+}
+
+// ExitError stops a running program that called exit(code), and can't be caught by a try statement.
+type ExitError struct {
+	Code int
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// A lexing or parsing error, which is also kept for tools that show errors where they are (ex: the language server).
+// Column starts at 1, or is 0 if it isn't known.
+type SyntaxError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// Prints error message to w (ex: os.Stderr)
+func ThrowError(w io.Writer, line int, message string) {
+	report(w, line, "Error: "+message)
+}
+
+// Number of frames printed from each end of a long stack trace (ex: after a stack overflow).
+const traceEdge = 10
+
+// Prints a runtime error followed by the stack trace of where it was raised.
+func ReportRuntimeError(w io.Writer, err RuntimeError) {
+	report(w, err.Line, "Runtime Error: "+err.Message)
+	for n, frame := range err.Trace {
+		if len(err.Trace) > 2*traceEdge && n >= traceEdge && n < len(err.Trace)-traceEdge {
+			if n == traceEdge {
+				fmt.Fprintf(w, "    ... %d more frames\n", len(err.Trace)-2*traceEdge)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "    %s\n", frame)
+	}
 }
 
 // Print any line dependant message (error, warning, etc.)
-func report(line int, message string) {
+func report(w io.Writer, line int, message string) {
 	if line == 0 {
-		fmt.Printf("%s\n", message)
+		fmt.Fprintf(w, "%s\n", message)
 	} else {
-		fmt.Printf("[Line %d] %s\n", line, message)
+		fmt.Fprintf(w, "[Line %d] %s\n", line, message)
 	}
-}
\ No newline at end of file
+}
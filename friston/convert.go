@@ -0,0 +1,325 @@
+package friston
+
+import (
+	"fmt"
+	"friston/errors"
+	"friston/interpreter"
+	"reflect"
+	"time"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Converts a Go value to a friston value. Numbers become float64, slices and arrays become
+// *interpreter.List, maps with string keys become *interpreter.Map, time.Time becomes interpreter.TimeValue
+// and funcs become friston functions.
+// Values that are already friston values (ex: functions from GetGlobal) are kept as they are.
+func ToValue(value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case nil, bool, float64, string, interpreter.Function, interpreter.ErrorValue, interpreter.TimeValue, *interpreter.List, *interpreter.Map:
+		return value, nil
+	case time.Time:
+		return interpreter.TimeValue{Time: value.(time.Time)}, nil
+	}
+
+	return toValue(reflect.ValueOf(value))
+}
+
+func toValue(v reflect.Value) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return ToValue(v.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+
+		list := &interpreter.List{}
+		for n := 0; n < v.Len(); n++ {
+			element, err := ToValue(v.Index(n).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list.Elements = append(list.Elements, element)
+		}
+		return list, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("friston: map keys must be strings, not %s", v.Type().Key())
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		m := interpreter.NewMap()
+		iter := v.MapRange()
+		for iter.Next() {
+			entry, err := ToValue(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			m.Entries[iter.Key().String()] = entry
+		}
+		return m, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return hostFunc{fn: v}, nil
+	}
+
+	return nil, fmt.Errorf("friston: can't convert a value of type %s", v.Type())
+}
+
+// Converts a friston value to a Go value. Numbers are float64, lists are []interface{}, maps are
+// map[string]interface{} and times are time.Time. Functions are returned as interpreter.Function, and can be passed to VM.Call.
+func FromValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *interpreter.List:
+		list := make([]interface{}, len(v.Elements))
+		for n, element := range v.Elements {
+			list[n] = FromValue(element)
+		}
+		return list
+	case *interpreter.Map:
+		m := make(map[string]interface{}, len(v.Entries))
+		for key, entry := range v.Entries {
+			m[key] = FromValue(entry)
+		}
+		return m
+	case interpreter.TimeValue:
+		return v.Time
+	case hostFunc:
+		return v.fn.Interface()
+	}
+
+	return value
+}
+
+// Converts a friston value to a Go value of type t, for the arguments of a host function.
+func toGo(i interpreter.Interpreter, value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected %s, but got nil", t)
+	}
+
+	if t == timeType {
+		tv, ok := value.(interpreter.TimeValue)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a time, but got %v", value)
+		}
+		return reflect.ValueOf(tv.Time), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// Numbers out of the type's range (ex: -1 or 300 for a uint8) would wrap around when converted.
+		number, ok := value.(float64)
+		if !ok || number != float64(int64(number)) || outOfRange(number, t) {
+			return reflect.Value{}, fmt.Errorf("expected a whole number, but got %v", value)
+		}
+		return reflect.ValueOf(number).Convert(t), nil
+	case reflect.Float32, reflect.Float64:
+		number, ok := value.(float64)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a number, but got %v", value)
+		}
+		return reflect.ValueOf(number).Convert(t), nil
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a string, but got %v", value)
+		}
+		return reflect.ValueOf(s).Convert(t), nil
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a bool, but got %v", value)
+		}
+		return reflect.ValueOf(b).Convert(t), nil
+	case reflect.Slice:
+		list, ok := value.(*interpreter.List)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a list, but got %v", value)
+		}
+
+		slice := reflect.MakeSlice(t, len(list.Elements), len(list.Elements))
+		for n, element := range list.Elements {
+			converted, err := toGo(i, element, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice.Index(n).Set(converted)
+		}
+		return slice, nil
+	case reflect.Map:
+		m, ok := value.(*interpreter.Map)
+		if !ok || t.Key().Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("expected a map, but got %v", value)
+		}
+
+		goMap := reflect.MakeMapWithSize(t, len(m.Entries))
+		for key, entry := range m.Entries {
+			converted, err := toGo(i, entry, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			goMap.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), converted)
+		}
+		return goMap, nil
+	case reflect.Func:
+		function, ok := value.(interpreter.Function)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a function, but got %v", value)
+		}
+		return makeFunc(i, function, t), nil
+	case reflect.Interface:
+		converted := reflect.ValueOf(FromValue(value))
+		if !converted.Type().AssignableTo(t) {
+			return reflect.Value{}, fmt.Errorf("expected %s, but got %v", t, value)
+		}
+		return converted, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("unsupported argument type %s", t)
+}
+
+// Whether a whole number doesn't fit in the integer type t.
+func outOfRange(number float64, t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number < 0 || reflect.Zero(t).OverflowUint(uint64(number))
+	}
+	return reflect.Zero(t).OverflowInt(int64(number))
+}
+
+// Wraps a friston function as a Go func of type t, so it can be passed to a host function as a callback.
+// Runtime errors in the callback stop the host function, and are raised in the calling program.
+func makeFunc(i interpreter.Interpreter, function interpreter.Function, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		var args []interface{}
+		for _, arg := range in {
+			converted, err := ToValue(arg.Interface())
+			if err != nil {
+				i.Throw(err.Error())
+			}
+			args = append(args, converted)
+		}
+
+		value, err := i.CallFunction(function, args)
+		if err != nil {
+			panic(err)
+		}
+
+		var out []reflect.Value
+		for n := 0; n < t.NumOut(); n++ {
+			if t.Out(n) == errorType {
+				out = append(out, reflect.Zero(errorType))
+				continue
+			}
+
+			converted, err := toGo(i, value, t.Out(n))
+			if err != nil {
+				i.Throw("Callback returned the wrong type: " + err.Error())
+			}
+			out = append(out, converted)
+		}
+		return out
+	})
+}
+
+// A Go function called from friston. Its arguments are converted to the func's parameter types,
+// and its results are converted back. A non-nil error result is raised as a runtime error.
+type hostFunc struct {
+	fn reflect.Value
+	// The name of the global it was set as, for errors.
+	name string
+}
+
+func (h hostFunc) Arity() interpreter.Arity {
+	t := h.fn.Type()
+	if t.IsVariadic() {
+		return interpreter.AtLeast(t.NumIn() - 1)
+	}
+	return interpreter.Exactly(t.NumIn())
+}
+
+func (h hostFunc) Call(i interpreter.Interpreter, args []interface{}) interface{} {
+	t := h.fn.Type()
+
+	var in []reflect.Value
+	for n, arg := range args {
+		// The extra arguments of a variadic func are converted to the element type of its last parameter.
+		var param reflect.Type
+		if t.IsVariadic() && n >= t.NumIn()-1 {
+			param = t.In(t.NumIn() - 1).Elem()
+		} else {
+			param = t.In(n)
+		}
+
+		converted, err := toGo(i, arg, param)
+		if err != nil {
+			i.Throw(fmt.Sprintf("Argument %d: %s.", n+1, err))
+		}
+		in = append(in, converted)
+	}
+
+	var value interface{}
+	for _, result := range h.call(i, in) {
+		if result.Type() == errorType {
+			if !result.IsNil() {
+				i.Throw(result.Interface().(error).Error())
+			}
+			continue
+		}
+
+		converted, err := ToValue(result.Interface())
+		if err != nil {
+			i.Throw(err.Error())
+		}
+		value = converted
+	}
+	return value
+}
+
+// Calls the Go function, raising a panic in it as a runtime error. Errors raised by friston callbacks it
+// called are passed on as they are.
+func (h hostFunc) call(i interpreter.Interpreter, in []reflect.Value) []reflect.Value {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case errors.RuntimeError, errors.AbortError, errors.ExitError:
+			panic(r)
+		default:
+			name := "Host function"
+			if h.name != "" {
+				name = h.name + "()"
+			}
+			i.Throw(fmt.Sprintf("%s panicked: %v", name, r))
+		}
+	}()
+	return h.fn.Call(in)
+}
+
+func (h hostFunc) String() string {
+	return "<native fn>"
+}
@@ -0,0 +1,205 @@
+// Package friston runs friston programs from Go, with values and functions provided by the host program.
+//
+//	vm := friston.NewVM(friston.Options{Script: "rules.fn"})
+//	vm.SetGlobal("limit", 10)
+//	vm.SetGlobal("lookup", func(key string) (float64, error) { ... })
+//	value, err := vm.Eval("lookup(\"a\") < limit")
+package friston
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"friston/interpreter"
+	"friston/lexer"
+	"friston/parser"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrSyntax is wrapped by the error Eval returns when the source can't be lexed or parsed,
+// which includes the syntax error messages.
+var ErrSyntax = errors.New("friston: syntax error")
+
+type Options struct {
+	// Name of the script shown in stack traces, defaults to "<script>".
+	Script string
+	// Maximum number of nested friston calls, defaults to interpreter.DefaultMaxCallDepth.
+	MaxCallDepth int
+	// Destination of program output (ex: println), defaults to os.Stdout.
+	Stdout io.Writer
+	// Destination of interpreter diagnostics, defaults to os.Stderr.
+	// Runtime errors from Eval and Call are returned rather than written here.
+	Stderr io.Writer
+	// Source of program input, defaults to os.Stdin.
+	Stdin io.Reader
+	// Maximum number of statements and loop iterations run by each Eval or Call, or 0 for no limit.
+	MaxSteps int
+	// Maximum wall-clock time of each Eval or Call, or 0 for no limit.
+	Timeout time.Duration
+	// Natives the program is allowed to call, ex: interpreter.Console | interpreter.Clock.
+	// By default only pure natives (ex: math and strings) are allowed.
+	Capabilities interpreter.Capabilities
+	// Directory imports are resolved from first, defaults to the working directory.
+	// Imports need the interpreter.Filesystem capability.
+	Dir string
+	// Directories imports are resolved from when they're not found in Dir.
+	SearchPath []string
+	// Source of the current time for clock(), now() and sleep(), ex: an interpreter.FakeClock in tests.
+	// Defaults to the system time.
+	Time interpreter.TimeSource
+}
+
+// A friston interpreter with its own global scope, which is kept between calls to Eval.
+// Programs that run out of steps, time out or are cancelled return an errors.AbortError,
+// which wraps interpreter.ErrStepLimit, context.DeadlineExceeded or context.Canceled.
+// Programs that call exit() (which needs interpreter.Process) return an errors.ExitError.
+type VM struct {
+	interpreter interpreter.Interpreter
+	timeout     time.Duration
+}
+
+func NewVM(opts Options) *VM {
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	if opts.Stdin == nil {
+		opts.Stdin = os.Stdin
+	}
+
+	vm := VM{}
+	vm.interpreter = interpreter.NewInterpreter(false, opts.Stdout, opts.Stderr, opts.Stdin, opts.Capabilities)
+
+	if opts.Script != "" {
+		vm.interpreter.Script = opts.Script
+	}
+	if opts.MaxCallDepth > 0 {
+		vm.interpreter.MaxCallDepth = opts.MaxCallDepth
+	}
+	vm.interpreter.MaxSteps = opts.MaxSteps
+	vm.interpreter.Dir = opts.Dir
+	vm.interpreter.SearchPath = opts.SearchPath
+	if opts.Time != nil {
+		vm.interpreter.Time = opts.Time
+	}
+	vm.timeout = opts.Timeout
+
+	return &vm
+}
+
+// Runs friston source code in the global scope.
+// Returns the value of the last statement if it is an expression, converted to a Go value.
+// Runtime errors are returned as errors.RuntimeError from the friston/errors package.
+func (vm *VM) Eval(src string) (interface{}, error) {
+	return vm.EvalContext(context.Background(), src)
+}
+
+// Like Eval, but the program is stopped when ctx is done.
+func (vm *VM) EvalContext(ctx context.Context, src string) (interface{}, error) {
+	var syntaxErrors bytes.Buffer
+
+	lex := lexer.NewLexer(src, false)
+	lex.Errors = &syntaxErrors
+	tokens, lexErr := lex.ScanTokens()
+	if lexErr {
+		return nil, fmt.Errorf("%w\n%s", ErrSyntax, strings.TrimSpace(syntaxErrors.String()))
+	}
+
+	par := parser.NewParser(tokens)
+	par.Errors = &syntaxErrors
+	stmts, parErr := par.Parse()
+	if parErr {
+		return nil, fmt.Errorf("%w\n%s", ErrSyntax, strings.TrimSpace(syntaxErrors.String()))
+	}
+
+	inter, cancel := vm.withContext(ctx)
+	defer cancel()
+
+	value, err := inter.Execute(stmts)
+	if err != nil {
+		return nil, err
+	}
+	return FromValue(value), nil
+}
+
+// Declares a global variable from a Go value. See ToValue for the supported types.
+func (vm *VM) SetGlobal(name string, value interface{}) error {
+	converted, err := ToValue(value)
+	if err != nil {
+		return err
+	}
+	if host, ok := converted.(hostFunc); ok {
+		host.name = name
+		converted = host
+	}
+
+	vm.interpreter.Define(name, converted)
+	return nil
+}
+
+// Returns the value of a global variable converted to a Go value, and whether it is declared.
+func (vm *VM) GetGlobal(name string) (interface{}, bool) {
+	value, ok := vm.interpreter.Lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return FromValue(value), true
+}
+
+// Calls a friston function with Go arguments. fn is either the name of a global function,
+// or a function value (ex: from GetGlobal). The result is converted to a Go value.
+func (vm *VM) Call(fn interface{}, args ...interface{}) (interface{}, error) {
+	return vm.CallContext(context.Background(), fn, args...)
+}
+
+// Like Call, but the function is stopped when ctx is done.
+func (vm *VM) CallContext(ctx context.Context, fn interface{}, args ...interface{}) (interface{}, error) {
+	if name, ok := fn.(string); ok {
+		value, declared := vm.interpreter.Lookup(name)
+		if !declared {
+			return nil, fmt.Errorf("friston: undefined function '%s'", name)
+		}
+		fn = value
+	}
+
+	function, ok := fn.(interpreter.Function)
+	if !ok {
+		return nil, fmt.Errorf("friston: can't call a value of type %T", fn)
+	}
+
+	var arguments []interface{}
+	for _, arg := range args {
+		converted, err := ToValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, converted)
+	}
+
+	inter, cancel := vm.withContext(ctx)
+	defer cancel()
+
+	value, err := inter.CallFunction(function, arguments)
+	if err != nil {
+		return nil, err
+	}
+	return FromValue(value), nil
+}
+
+// Returns the interpreter set to stop when ctx is done, or after the VM's timeout.
+func (vm *VM) withContext(ctx context.Context) (interpreter.Interpreter, context.CancelFunc) {
+	cancel := func() {}
+	if vm.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, vm.timeout)
+	}
+
+	inter := vm.interpreter
+	inter.Context = ctx
+	return inter, cancel
+}
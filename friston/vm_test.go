@@ -0,0 +1,269 @@
+package friston
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"friston/errors"
+	"friston/interpreter"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToValue(t *testing.T) {
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{3, 3.0},
+		{uint8(7), 7.0},
+		{float32(1.5), 1.5},
+		{"text", "text"},
+		{when, interpreter.TimeValue{Time: when}},
+		{[]int(nil), nil},
+		{[]int{1, 2}, &interpreter.List{Elements: []interface{}{1.0, 2.0}}},
+		{[2]string{"a", "b"}, &interpreter.List{Elements: []interface{}{"a", "b"}}},
+	}
+	for _, test := range tests {
+		got, err := ToValue(test.value)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ToValue(%#v) = %#v, %v, want %#v", test.value, got, err, test.want)
+		}
+	}
+
+	m, err := ToValue(map[string][]int{"a": {1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := m.(*interpreter.Map).Entries
+	if !reflect.DeepEqual(entries["a"], &interpreter.List{Elements: []interface{}{1.0}}) {
+		t.Errorf("ToValue(map) entries = %#v", entries)
+	}
+
+	for _, value := range []interface{}{map[int]string{1: "a"}, make(chan int), struct{}{}} {
+		if _, err := ToValue(value); err == nil {
+			t.Errorf("ToValue(%#v) didn't fail", value)
+		}
+	}
+}
+
+func TestFromValue(t *testing.T) {
+	m := interpreter.NewMap()
+	m.Entries["xs"] = &interpreter.List{Elements: []interface{}{1.0, "b", nil}}
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	m.Entries["when"] = interpreter.TimeValue{Time: when}
+
+	want := map[string]interface{}{"xs": []interface{}{1.0, "b", nil}, "when": when}
+	if got := FromValue(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("FromValue(map) = %#v, want %#v", got, want)
+	}
+
+	// Values converted to friston and back are the same.
+	for _, value := range []interface{}{nil, true, 2.5, "s", []interface{}{1.0, []interface{}{"x"}}, map[string]interface{}{"k": false}} {
+		converted, err := ToValue(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FromValue(converted); !reflect.DeepEqual(got, value) {
+			t.Errorf("FromValue(ToValue(%#v)) = %#v", value, got)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	vm := NewVM(Options{})
+	if err := vm.SetGlobal("limit", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vm.Eval("let doubled = limit * 2"); err != nil {
+		t.Fatal(err)
+	}
+	// The global scope is kept between calls.
+	value, err := vm.Eval("doubled + 1")
+	if err != nil || value != 21.0 {
+		t.Fatalf("Eval = %v, %v, want 21", value, err)
+	}
+	if value, ok := vm.GetGlobal("doubled"); !ok || value != 20.0 {
+		t.Fatalf("GetGlobal = %v, %v, want 20", value, ok)
+	}
+	if _, ok := vm.GetGlobal("missing"); ok {
+		t.Fatal("GetGlobal of an undeclared variable is ok")
+	}
+
+	if _, err := vm.Eval("let = 1"); !goerrors.Is(err, ErrSyntax) {
+		t.Fatalf("Eval of a syntax error = %v, want ErrSyntax", err)
+	}
+	if _, err := vm.Eval("undeclared + 1"); err == nil {
+		t.Fatal("Eval of an undeclared variable didn't fail")
+	} else if _, ok := err.(errors.RuntimeError); !ok {
+		t.Fatalf("Eval of an undeclared variable = %#v, want a RuntimeError", err)
+	}
+}
+
+func TestHostFunctions(t *testing.T) {
+	vm := NewVM(Options{})
+	vm.SetGlobal("lookup", func(key string) (float64, error) {
+		if key == "a" {
+			return 1.5, nil
+		}
+		return 0, fmt.Errorf("no key '%s'", key)
+	})
+	vm.SetGlobal("sum", func(numbers ...int) int {
+		total := 0
+		for _, n := range numbers {
+			total += n
+		}
+		return total
+	})
+	vm.SetGlobal("apply", func(f func(int) int, n int) int {
+		return f(n)
+	})
+
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{`lookup("a") * 2`, 3.0},
+		{`sum()`, 0.0},
+		{`sum(1, 2, 3)`, 6.0},
+		{"function square: n =\n    return n * n\napply(square, 4)", 16.0},
+	}
+	for _, test := range tests {
+		value, err := vm.Eval(test.src)
+		if err != nil || value != test.want {
+			t.Errorf("Eval(%q) = %v, %v, want %v", test.src, value, err, test.want)
+		}
+	}
+
+	errorTests := []struct {
+		src  string
+		want string
+	}{
+		{`lookup("b")`, "no key 'b'"},
+		{`lookup(1)`, "Argument 1: expected a string"},
+		{`sum(1.5)`, "expected a whole number"},
+		{`lookup()`, "Expected"},
+	}
+	for _, test := range errorTests {
+		_, err := vm.Eval(test.src)
+		runtimeErr, ok := err.(errors.RuntimeError)
+		if !ok || !strings.Contains(runtimeErr.Message, test.want) {
+			t.Errorf("Eval(%q) = %v, want an error containing %q", test.src, err, test.want)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	vm := NewVM(Options{})
+	if _, err := vm.Eval("function greet: name =\n    return \"hi \" + name"); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := vm.Call("greet", "bob")
+	if err != nil || value != "hi bob" {
+		t.Fatalf("Call(\"greet\") = %v, %v", value, err)
+	}
+
+	greet, _ := vm.GetGlobal("greet")
+	if value, err := vm.Call(greet, "ann"); err != nil || value != "hi ann" {
+		t.Fatalf("Call(greet) = %v, %v", value, err)
+	}
+
+	if _, err := vm.Call("missing"); err == nil {
+		t.Fatal("Call of an undeclared function didn't fail")
+	}
+	if _, err := vm.Call(1.0); err == nil {
+		t.Fatal("Call of a number didn't fail")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	forever := "while true then\n    let x = 1"
+
+	vm := NewVM(Options{MaxSteps: 100})
+	if _, err := vm.Eval(forever); !goerrors.Is(err, interpreter.ErrStepLimit) {
+		t.Fatalf("Eval with MaxSteps = %v, want ErrStepLimit", err)
+	}
+
+	vm = NewVM(Options{Timeout: 10 * time.Millisecond})
+	if _, err := vm.Eval(forever); !goerrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Eval with a Timeout = %v, want context.DeadlineExceeded", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	vm = NewVM(Options{})
+	if _, err := vm.EvalContext(ctx, forever); !goerrors.Is(err, context.Canceled) {
+		t.Fatalf("EvalContext after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestHostFunctionIntegerRanges(t *testing.T) {
+	vm := NewVM(Options{})
+	vm.SetGlobal("byte", func(n uint8) uint8 { return n })
+	vm.SetGlobal("small", func(n int8) int8 { return n })
+	vm.SetGlobal("short", func(n int16) int16 { return n })
+	vm.SetGlobal("word", func(n uint32) uint32 { return n })
+
+	valid := []struct {
+		src  string
+		want float64
+	}{
+		{"byte(0)", 0},
+		{"byte(255)", 255},
+		{"small(-128)", -128},
+		{"small(127)", 127},
+		{"short(-32768)", -32768},
+		{"word(4294967295)", 4294967295},
+	}
+	for _, test := range valid {
+		value, err := vm.Eval(test.src)
+		if err != nil || value != test.want {
+			t.Errorf("Eval(%q) = %v, %v, want %v", test.src, value, err, test.want)
+		}
+	}
+
+	invalid := []string{"byte(-1)", "byte(256)", "byte(300)", "small(128)", "small(-129)", "short(32768)", "word(-1)", "word(4294967296)"}
+	for _, src := range invalid {
+		_, err := vm.Eval(src)
+		runtimeErr, ok := err.(errors.RuntimeError)
+		if !ok || !strings.Contains(runtimeErr.Message, "expected a whole number") {
+			t.Errorf("Eval(%q) = %v, want an error for a number out of range", src, err)
+		}
+	}
+}
+
+func TestHostFunctionPanics(t *testing.T) {
+	vm := NewVM(Options{})
+	vm.SetGlobal("explode", func() { panic("boom") })
+	vm.SetGlobal("index", func(xs []int, n int) int { return xs[n] })
+	vm.SetGlobal("apply", func(f func() int) int { return f() })
+
+	_, err := vm.Eval("explode()")
+	runtimeErr, ok := err.(errors.RuntimeError)
+	if !ok || runtimeErr.Message != "explode() panicked: boom" {
+		t.Fatalf("Eval(explode()) = %#v, want a runtime error naming explode", err)
+	}
+
+	_, err = vm.Eval("index([1], 5)")
+	if runtimeErr, ok := err.(errors.RuntimeError); !ok || !strings.Contains(runtimeErr.Message, "index out of range") {
+		t.Fatalf("Eval(index()) = %#v, want a runtime error", err)
+	}
+
+	value, err := vm.Eval("let caught = \"\"\ntry then\n    explode()\ncatch e then\n    caught = \"yes\"\ncaught")
+	if err != nil || value != "yes" {
+		t.Fatalf("caught = %v, %v, want yes", value, err)
+	}
+
+	// Errors thrown by a callback aren't wrapped.
+	_, err = vm.Eval("function fail: =\n    throw \"failed\"\napply(fail)")
+	if runtimeErr, ok := err.(errors.RuntimeError); !ok || strings.Contains(runtimeErr.Message, "panicked") {
+		t.Fatalf("Eval(apply(fail)) = %#v, want the callback's error", err)
+	}
+}
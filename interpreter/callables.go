@@ -1,38 +1,225 @@
 package interpreter
 
 import (
+	"fmt"
 	"friston/ast"
 	"friston/environment"
 	"friston/lexer"
+	"sort"
 )
 
 type Function interface {
 	Call(i Interpreter, args []interface{}) interface{}
-	Arity() int
+	Arity() Arity
 }
 
+// Max is Variadic for functions that take any number of arguments from Min.
+const Variadic = -1
+
+// The number of arguments a function can be called with.
+type Arity struct {
+	Min int
+	Max int
+}
+
+func Exactly(n int) Arity { return Arity{n, n} }
+
+func AtLeast(n int) Arity { return Arity{n, Variadic} }
+
+func Between(min int, max int) Arity { return Arity{min, max} }
+
+func (a Arity) Accepts(count int) bool {
+	return count >= a.Min && (a.Max == Variadic || count <= a.Max)
+}
+
+// Describes the arity for error messages, ex: "at least 1 argument"
+func (a Arity) String() string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+
+	switch {
+	case a.Max == Variadic:
+		return "at least " + plural(a.Min)
+	case a.Min == a.Max:
+		return plural(a.Min)
+	default:
+		return fmt.Sprintf("%d to %s", a.Min, plural(a.Max))
+	}
+}
+
+// Defaults holds the default value of each parameter, or nil if it has none.
+// If Rest is set, the last parameter is a list of the remaining arguments.
 type UserFunc struct {
 	Identifier lexer.Token
 	Parameters []string
+	Defaults   []ast.Expression
+	Rest       bool
 	Block      ast.Block
 	Closure    environment.Environment
+	File       string
 }
 
 func (u UserFunc) Call(i Interpreter, args []interface{}) interface{} {
-	// Call a function within it's eclosed environment, making an environment chain all the way up to globals through nested functions.
-	i.environment = environment.NewEnclosed(u.Closure)
+	function := u
 
-	for n, arg := range args {
-		i.environment.Declare(u.Parameters[n], arg)
+	// Tail calls are returned from the block instead of being called, and are run here in the same frame.
+	for {
+		// Call a function within it's eclosed environment, making an environment chain all the way up to globals through nested functions.
+		i.environment = environment.NewEnclosed(function.Closure)
+
+		function.bind(i, args)
+
+		ret, ok := i.executeBlock(function.Block).(returnValue)
+		if !ok {
+			return nil
+		}
+
+		tail, ok := ret.value.(tailCall)
+		if !ok {
+			return ret.value
+		}
+
+		// The tail call replaces this function's frame, but keeps the original call site.
+		function, args = tail.function, tail.args
+		i.frames[len(i.frames)-1].name = function.Identifier.Lexeme
+		i.frames[len(i.frames)-1].file = function.File
 	}
+}
 
-	value := i.executeBlock(u.Block)
-	return value
+// Declares the parameters in the current environment, so defaults can use the parameters before them.
+func (u UserFunc) bind(i Interpreter, args []interface{}) {
+	for n, param := range u.Parameters {
+		if u.Rest && n == len(u.Parameters)-1 {
+			rest := &List{}
+			if n < len(args) {
+				rest.Elements = append(rest.Elements, args[n:]...)
+			}
+			i.environment.Declare(param, rest)
+		} else if n < len(args) && args[n] != (missingArg{}) {
+			i.environment.Declare(param, args[n])
+		} else {
+			i.environment.Declare(param, i.evaluate(u.Defaults[n]))
+		}
+	}
 }
 
-func (u UserFunc) Arity() int { return len(u.Parameters) }
+// An argument skipped over by a keyword argument, so its parameter takes its default value.
+type missingArg struct{}
+
+// Matches keyword arguments to parameters by name, returning all the arguments in parameter order.
+func (u UserFunc) keywordArgs(args []interface{}, keywords map[string]interface{}) ([]interface{}, error) {
+	// The rest parameter only collects positional arguments.
+	named := len(u.Parameters)
+	if u.Rest {
+		named--
+	}
+
+	all := append([]interface{}{}, args...)
+	for _, name := range sortedNames(keywords) {
+		n := 0
+		for n < named && u.Parameters[n] != name {
+			n++
+		}
+
+		if n == named {
+			return nil, fmt.Errorf("Unknown keyword argument '%s'.", name)
+		}
+		if n < len(args) {
+			return nil, fmt.Errorf("Argument '%s' is given more than once.", name)
+		}
+
+		for len(all) <= n {
+			all = append(all, missingArg{})
+		}
+		all[n] = keywords[name]
+	}
+
+	for n, arg := range all {
+		if arg == (missingArg{}) && (n >= len(u.Defaults) || u.Defaults[n] == nil) {
+			return nil, fmt.Errorf("Missing argument '%s'.", u.Parameters[n])
+		}
+	}
+	return all, nil
+}
+
+// Natives that take keyword options list their names, and read them with Interpreter.Option, ex: println(a, b, sep: ", ")
+type OptionsFunction interface {
+	Function
+	Options() []string
+}
+
+// Checks that a function other than a UserFunc takes each of the keyword options it's called with.
+func checkOptions(function Function, options map[string]interface{}) error {
+	var allowed []string
+	if f, ok := function.(OptionsFunction); ok {
+		allowed = f.Options()
+	}
+
+	for _, name := range sortedNames(options) {
+		found := false
+		for _, option := range allowed {
+			found = found || option == name
+		}
+		if !found {
+			return fmt.Errorf("Unknown keyword argument '%s'.", name)
+		}
+	}
+	return nil
+}
+
+// Keyword names are checked in order, so the same call always reports the same error.
+func sortedNames(keywords map[string]interface{}) []string {
+	var names []string
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (u UserFunc) Arity() Arity {
+	params := len(u.Parameters)
+	if u.Rest {
+		params--
+	}
+
+	required := 0
+	for n := 0; n < params; n++ {
+		if n >= len(u.Defaults) || u.Defaults[n] == nil {
+			required++
+		}
+	}
+
+	if u.Rest {
+		return AtLeast(required)
+	}
+	return Between(required, params)
+}
 
 // String representation to allow code to print UserFunction types.
 func (u UserFunc) String() string {
 	return "<fn " + u.Identifier.Lexeme + ">"
 }
+
+// A call in tail position, to be run by the calling UserFunc instead of growing the stack.
+type tailCall struct {
+	function UserFunc
+	args     []interface{}
+}
+
+// Wraps the value of a return statement so it is passed up through enclosing blocks, loops and ifs.
+type returnValue struct {
+	value interface{}
+}
+
+// A UserFunc call on the friston call stack, the token it was called from, and the caller's scope.
+type callFrame struct {
+	name   string
+	file   string
+	call   lexer.Token
+	caller environment.Environment
+}
@@ -0,0 +1,87 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capabilities are the kinds of access to the outside world that a program is allowed, combined with '|'.
+// An interpreter is only allowed the natives whose capabilities it was created with.
+type Capabilities uint
+
+const (
+	// Pure natives only compute values (ex: math and strings), and are always allowed.
+	Pure Capabilities = 0
+	// Printing to the interpreter's output, and reading its input.
+	Console Capabilities = 1 << 0
+	// Reading and writing files.
+	Filesystem Capabilities = 1 << 1
+	// Reading the current time, and sleeping.
+	Clock Capabilities = 1 << 2
+	// Reading environment variables.
+	Env Capabilities = 1 << 3
+	// Starting and exiting processes.
+	Process Capabilities = 1 << 4
+
+	AllCapabilities = Console | Filesystem | Clock | Env | Process
+)
+
+var capabilityNames = []struct {
+	capability Capabilities
+	name       string
+}{
+	{Console, "console"},
+	{Filesystem, "filesystem"},
+	{Clock, "clock"},
+	{Env, "env"},
+	{Process, "process"},
+}
+
+// Lists capabilities by name, ex: "console, clock".
+func (c Capabilities) String() string {
+	if c == Pure {
+		return "pure"
+	}
+
+	var names []string
+	for _, capability := range capabilityNames {
+		if c&capability.capability != 0 {
+			names = append(names, capability.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Checks whether every capability in needs is allowed.
+func (c Capabilities) Allows(needs Capabilities) bool {
+	return c&needs == needs
+}
+
+// A native function, and the capabilities a program needs to call it.
+type Native struct {
+	Function
+	Needs Capabilities
+}
+
+// Replaces a native that the interpreter isn't allowed, raising a permission error when it's called.
+type deniedNative struct {
+	Native
+	name string
+}
+
+func (d deniedNative) Call(i Interpreter, args []interface{}) interface{} {
+	i.Throw(fmt.Sprintf("Permission denied, '%s' needs the %s capability.", d.name, d.Needs))
+	return nil
+}
+
+// Denied natives still take their options, so calls with them raise a permission error.
+func (d deniedNative) Options() []string {
+	if f, ok := d.Function.(OptionsFunction); ok {
+		return f.Options()
+	}
+	return nil
+}
+
+func (d deniedNative) String() string {
+	return "<native fn " + d.name + ">"
+}
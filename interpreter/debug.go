@@ -0,0 +1,72 @@
+package interpreter
+
+import (
+	"friston/ast"
+	"friston/environment"
+	"friston/errors"
+	"path/filepath"
+)
+
+// A Debugger is called before each statement the interpreter runs (other than blocks), and pauses the program
+// until it returns. The interpreter it's given is paused at the statement, so it can be inspected with
+// CallDepth, StackTrace, Scopes and Eval.
+type Debugger interface {
+	Before(i Interpreter, stmt ast.Statement)
+}
+
+// Returns the number of function calls (and module imports) the interpreter is in.
+func (i Interpreter) CallDepth() int {
+	return len(i.frames)
+}
+
+// Returns the call stack from the innermost call outwards, where line is the current line of the innermost call.
+func (i Interpreter) StackTrace(line int) []errors.Frame {
+	return i.stackTrace(line)
+}
+
+// Returns the interpreter as it was paused in an outer call, where 0 is the innermost call and CallDepth() is
+// the script. Its Scopes and Eval are of the scope the call was made from.
+func (i Interpreter) Frame(n int) Interpreter {
+	if n <= 0 || n > len(i.frames) {
+		return i
+	}
+	i.environment = i.frames[len(i.frames)-n].caller
+	i.frames = i.frames[:len(i.frames)-n]
+	return i
+}
+
+// Returns the path of an imported module from the file name in its stack frames, if it's loaded or loading.
+func (i Interpreter) ModulePath(file string) (string, bool) {
+	for _, path := range i.modules.loading {
+		if filepath.Base(path) == file {
+			return path, true
+		}
+	}
+	for path := range i.modules.loaded {
+		if filepath.Base(path) == file {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Returns the variables of each scope, from the current one out to the globals, which are last.
+func (i Interpreter) Scopes() []map[string]interface{} {
+	var scopes []map[string]interface{}
+	env := i.environment
+	for {
+		scopes = append(scopes, env.Values)
+
+		parent, ok := env.GetParent().(environment.Environment)
+		if !ok {
+			return scopes
+		}
+		env = parent
+	}
+}
+
+// Evaluates an expression in the current scope (ex: of a paused program), returning the error if it raises one.
+func (i Interpreter) Eval(expr ast.Expression) (value interface{}, err error) {
+	defer recoverRun(&err)
+	return i.evaluate(expr), nil
+}
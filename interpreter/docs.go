@@ -0,0 +1,84 @@
+package interpreter
+
+// Describes natives and constants for editors (ex: hover in the language server).
+type Doc struct {
+	Signature string
+	Text      string
+}
+
+// Docs has an entry for every native and constant.
+var Docs = map[string]Doc{
+	"clock":      {"clock()", "Returns the Unix time in seconds."},
+	"println":    {"println(values..., sep: \" \")", "Prints the values separated by sep, then a new line."},
+	"print":      {"print(values..., sep: \" \")", "Prints the values separated by sep."},
+	"stacktrace": {"stacktrace()", "Returns the current call stack, one \"at function (file:line)\" frame per line."},
+
+	"len":        {"len(value)", "Returns the length of a string, list or map."},
+	"substr":     {"substr(s, start, end)", "Returns the characters of s from start up to, but not including, end (or the end of s)."},
+	"split":      {"split(s, sep)", "Returns a list of the parts of s between each sep."},
+	"join":       {"join(list, sep)", "Returns the elements of list, as they would be printed, separated by sep (or nothing)."},
+	"trim":       {"trim(s)", "Removes whitespace from both ends of a string."},
+	"upper":      {"upper(s)", "Returns s in upper case."},
+	"lower":      {"lower(s)", "Returns s in lower case."},
+	"replace":    {"replace(s, old, new)", "Replaces every old in s with new."},
+	"contains":   {"contains(s, sub)", "Returns whether s contains sub."},
+	"startsWith": {"startsWith(s, prefix)", "Returns whether s starts with prefix."},
+	"indexOf":    {"indexOf(s, sub)", "Returns the index of the first sub in s, or -1 if s doesn't contain it."},
+	"repeat":     {"repeat(s, n)", "Returns s repeated n times, up to 256 MB."},
+	"format":     {"format(template, values...)", "Formats values with printf verbs, ex: format(\"%s: %.2f\", name, total)"},
+	"str":        {"str(value)", "Converts any value to a string, as it would be printed."},
+	"num":        {"num(s)", "Converts a string to a number, ex: num(\" 4.5 \") is 4.5"},
+
+	"sqrt":      {"sqrt(x)", "Returns the square root of x."},
+	"abs":       {"abs(x)", "Returns the absolute value of x."},
+	"floor":     {"floor(x)", "Rounds x down to a whole number."},
+	"ceil":      {"ceil(x)", "Rounds x up to a whole number."},
+	"round":     {"round(x)", "Rounds x to the nearest whole number, with halves rounded away from zero."},
+	"sin":       {"sin(x)", "Returns the sine of x radians."},
+	"cos":       {"cos(x)", "Returns the cosine of x radians."},
+	"tan":       {"tan(x)", "Returns the tangent of x radians."},
+	"asin":      {"asin(x)", "Returns the arcsine of x, in radians."},
+	"acos":      {"acos(x)", "Returns the arccosine of x, in radians."},
+	"atan":      {"atan(x)", "Returns the arctangent of x, in radians."},
+	"exp":       {"exp(x)", "Returns e to the power of x."},
+	"log":       {"log(x)", "Returns the natural logarithm of x."},
+	"log10":     {"log10(x)", "Returns the base 10 logarithm of x."},
+	"log2":      {"log2(x)", "Returns the base 2 logarithm of x."},
+	"pow":       {"pow(x, y)", "Returns x to the power of y."},
+	"atan2":     {"atan2(y, x)", "Returns the angle of the point (x, y) from the x axis, in radians."},
+	"min":       {"min(numbers...)", "Returns the smallest of the numbers."},
+	"max":       {"max(numbers...)", "Returns the largest of the numbers."},
+	"random":    {"random()", "Returns a random number from 0 up to, but not including, 1."},
+	"randomInt": {"randomInt(min, max)", "Returns a random whole number from min to max, including max."},
+	"seed":      {"seed(n)", "Seeds the random source, so the numbers that follow are always the same."},
+	"pi":        {"pi", "The ratio of a circle's circumference to its diameter, a constant."},
+	"e":         {"e", "Euler's number, the base of natural logarithms, a constant."},
+
+	"readFile":   {"readFile(path)", "Returns the contents of a file."},
+	"writeFile":  {"writeFile(path, s)", "Replaces the contents of a file with s, creating it if it doesn't exist."},
+	"appendFile": {"appendFile(path, s)", "Adds s to the end of a file, creating it if it doesn't exist."},
+	"listDir":    {"listDir(path)", "Returns a sorted list of the names in a directory."},
+	"exists":     {"exists(path)", "Returns whether a file or directory exists."},
+	"eachLine":   {"eachLine(path, fn)", "Calls fn with each line of a file, without its line ending. Returning false from fn stops early."},
+	"readLine":   {"readLine()", "Returns the next line of input without its line ending, or nil at the end of the input."},
+
+	"jsonParse":     {"jsonParse(s)", "Returns the value of a JSON string, with objects as maps, arrays as lists and null as nil."},
+	"jsonStringify": {"jsonStringify(value, indent)", "Returns value as JSON, with object keys sorted. If indent is given (a number of spaces, or a string, of up to 10) each element is on its own line."},
+
+	"exit":   {"exit(code)", "Stops the program, which exits with code (or 0). Finally branches still run, but try can't catch it."},
+	"getEnv": {"getEnv(name)", "Returns the value of an environment variable, or nil if it isn't set."},
+	"setEnv": {"setEnv(name, value)", "Sets an environment variable, which processes started later inherit. A value of nil unsets it."},
+	"env":    {"env()", "Returns a map of every environment variable."},
+
+	"now":        {"now()", "Returns the current time, in the local time zone."},
+	"sleep":      {"sleep(ms)", "Waits for a number of milliseconds."},
+	"date":       {"date(year, month, day, hour, minute, second, zone: \"UTC\")", "Returns the time of a date, where the time of day is optional. Out of range components are normalized, ex: date(2024, 1, 32) is February 1st."},
+	"fromUnix":   {"fromUnix(seconds)", "Returns the time of a Unix timestamp, in UTC."},
+	"formatTime": {"formatTime(t, layout)", "Formats a time with a Go reference layout (ex: \"Jan 2, 2006\") or a named layout (ex: \"date\"). The layout defaults to RFC 3339."},
+	"parseTime":  {"parseTime(s, layout, zone: \"UTC\")", "Parses a time with a layout, like formatTime. The zone is used when s doesn't include one."},
+	"inZone":     {"inZone(t, zone)", "Returns the same time in another time zone, ex: inZone(t, \"America/New_York\")"},
+	"addTime":    {"addTime(t, ms)", "Returns the time a number of milliseconds after t (or before, if negative)."},
+	"addDate":    {"addDate(t, years, months, days)", "Moves a time by calendar days in its time zone, so the time of day stays the same across daylight saving changes."},
+	"timeDiff":   {"timeDiff(a, b)", "Returns the number of milliseconds from b to a."},
+	"duration":   {"duration(s)", "Returns the milliseconds in a duration like \"1h30m\" or \"250ms\"."},
+}
@@ -0,0 +1,166 @@
+package interpreter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// File natives need the Filesystem capability, and readLine (from the interpreter's input) needs Console.
+// Paths are relative to the working directory.
+func init() {
+	registerNatives(map[string]Native{
+		"readFile":   {readFileNative{}, Filesystem},
+		"writeFile":  {writeFileNative{"writeFile", os.O_TRUNC}, Filesystem},
+		"appendFile": {writeFileNative{"appendFile", os.O_APPEND}, Filesystem},
+		"listDir":    {listDirNative{}, Filesystem},
+		"exists":     {existsNative{}, Filesystem},
+		"eachLine":   {eachLineNative{}, Filesystem},
+		"readLine":   {readLineNative{}, Console},
+	})
+}
+
+// Raises a Go I/O error as a runtime error, ex: "readFile() can't open 'data.txt', no such file or directory."
+func (i Interpreter) fileError(native string, err error) {
+	if pathErr, ok := err.(*os.PathError); ok {
+		i.Throw(fmt.Sprintf("%s() can't %s '%s', %s.", native, pathErr.Op, pathErr.Path, pathErr.Err))
+	}
+	i.Throw(fmt.Sprintf("%s() %s.", native, err))
+}
+
+// readFile(path) returns the contents of a file.
+type readFileNative struct{}
+
+func (r readFileNative) Arity() Arity { return Exactly(1) }
+
+func (r readFileNative) Call(i Interpreter, args []interface{}) interface{} {
+	contents, err := ioutil.ReadFile(i.stringArg("readFile", args, 0))
+	if err != nil {
+		i.fileError("readFile", err)
+	}
+	return string(contents)
+}
+
+// writeFile(path, s) replaces the contents of a file with s, and appendFile(path, s) adds s to the end.
+// Both create the file if it doesn't exist.
+type writeFileNative struct {
+	name string
+	mode int
+}
+
+func (w writeFileNative) Arity() Arity { return Exactly(2) }
+
+func (w writeFileNative) Call(i Interpreter, args []interface{}) interface{} {
+	path := i.stringArg(w.name, args, 0)
+	contents := i.stringArg(w.name, args, 1)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|w.mode, 0644)
+	if err != nil {
+		i.fileError(w.name, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(contents); err != nil {
+		i.fileError(w.name, err)
+	}
+	return nil
+}
+
+func (w writeFileNative) String() string {
+	return "<native fn " + w.name + ">"
+}
+
+// listDir(path) returns a sorted list of the names in a directory.
+type listDirNative struct{}
+
+func (l listDirNative) Arity() Arity { return Exactly(1) }
+
+func (l listDirNative) Call(i Interpreter, args []interface{}) interface{} {
+	entries, err := ioutil.ReadDir(i.stringArg("listDir", args, 0))
+	if err != nil {
+		i.fileError("listDir", err)
+	}
+
+	list := &List{}
+	for _, entry := range entries {
+		list.Elements = append(list.Elements, entry.Name())
+	}
+	return list
+}
+
+// exists(path) returns whether a file or directory exists.
+type existsNative struct{}
+
+func (e existsNative) Arity() Arity { return Exactly(1) }
+
+func (e existsNative) Call(i Interpreter, args []interface{}) interface{} {
+	_, err := os.Stat(i.stringArg("exists", args, 0))
+	if os.IsNotExist(err) {
+		return false
+	} else if err != nil {
+		i.fileError("exists", err)
+	}
+	return true
+}
+
+// eachLine(path, fn) calls fn with each line of a file, without its line ending. Returning false from fn stops early.
+type eachLineNative struct{}
+
+func (e eachLineNative) Arity() Arity { return Exactly(2) }
+
+func (e eachLineNative) Call(i Interpreter, args []interface{}) interface{} {
+	path := i.stringArg("eachLine", args, 0)
+	fn := i.functionArg("eachLine", args, 1)
+
+	file, err := os.Open(path)
+	if err != nil {
+		i.fileError("eachLine", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			i.fileError("eachLine", err)
+		}
+
+		if result := i.invoke(fn, line); result == false {
+			return nil
+		}
+	}
+}
+
+// readLine() returns the next line of input without its line ending, or nil at the end of the input.
+type readLineNative struct{}
+
+func (r readLineNative) Arity() Arity { return Exactly(0) }
+
+func (r readLineNative) Call(i Interpreter, args []interface{}) interface{} {
+	if i.stdin == nil {
+		return nil
+	}
+
+	line, err := readLine(i.stdin)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		i.fileError("readLine", err)
+	}
+	return line
+}
+
+// Reads a line, removing its "\n" or "\r\n". The last line doesn't need a line ending, and io.EOF is returned after it.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), err
+}
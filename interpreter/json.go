@@ -0,0 +1,135 @@
+package interpreter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// JSON natives. Objects are maps, arrays are lists, and null is nil.
+func init() {
+	registerNatives(map[string]Native{
+		"jsonParse":     {jsonParseNative{}, Pure},
+		"jsonStringify": {jsonStringifyNative{}, Pure},
+	})
+}
+
+// jsonParse(s) returns the value of a JSON string.
+type jsonParseNative struct{}
+
+func (j jsonParseNative) Arity() Arity { return Exactly(1) }
+
+func (j jsonParseNative) Call(i Interpreter, args []interface{}) interface{} {
+	var value interface{}
+	err := json.Unmarshal([]byte(i.stringArg("jsonParse", args, 0)), &value)
+	if syntaxErr, ok := err.(*json.SyntaxError); ok {
+		i.Throw(fmt.Sprintf("jsonParse() invalid JSON at offset %d, %s.", syntaxErr.Offset, syntaxErr))
+	} else if err != nil {
+		i.Throw(fmt.Sprintf("jsonParse() %s.", err))
+	}
+	return fromJSON(value)
+}
+
+// Converts a decoded JSON value to a friston value.
+func fromJSON(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []interface{}:
+		list := &List{}
+		for _, element := range value {
+			list.Elements = append(list.Elements, fromJSON(element))
+		}
+		return list
+	case map[string]interface{}:
+		m := NewMap()
+		for key, entry := range value {
+			m.Entries[key] = fromJSON(entry)
+		}
+		return m
+	}
+
+	// Strings, numbers (float64), bools and nil are the same in friston.
+	return value
+}
+
+// jsonStringify(value, indent) returns value as JSON, with object keys sorted.
+// If indent is given (a number of spaces, or a string) each element is on its own line.
+// Like JavaScript's JSON.stringify, indents are cut down to maxIndent spaces or characters.
+type jsonStringifyNative struct{}
+
+const maxIndent = 10
+
+func (j jsonStringifyNative) Arity() Arity { return Between(1, 2) }
+
+func (j jsonStringifyNative) Call(i Interpreter, args []interface{}) interface{} {
+	indent := ""
+	if len(args) > 1 {
+		switch arg := args[1].(type) {
+		case string:
+			indent = arg
+			if len(indent) > maxIndent {
+				indent = indent[:maxIndent]
+			}
+		default:
+			spaces := i.intArg("jsonStringify", args, 1)
+			if spaces < 0 {
+				i.Throw("jsonStringify() indent must not be negative.")
+			} else if spaces > maxIndent {
+				spaces = maxIndent
+			}
+			indent = strings.Repeat(" ", spaces)
+		}
+	}
+
+	value, err := toJSON(args[0])
+	if err != nil {
+		i.Throw("jsonStringify() " + err.Error())
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(value); err != nil {
+		i.Throw(fmt.Sprintf("jsonStringify() %s.", err))
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// Converts a friston value to a value encoding/json can encode.
+func toJSON(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case nil, bool, string:
+		return value, nil
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("can't convert %v to JSON.", value)
+		}
+		return value, nil
+	case *List:
+		elements := make([]interface{}, len(value.Elements))
+		for n, element := range value.Elements {
+			converted, err := toJSON(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[n] = converted
+		}
+		return elements, nil
+	case *Map:
+		entries := make(map[string]interface{}, len(value.Entries))
+		for key, entry := range value.Entries {
+			converted, err := toJSON(entry)
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = converted
+		}
+		return entries, nil
+	case Function:
+		return nil, fmt.Errorf("can't convert a function to JSON.")
+	}
+
+	return nil, fmt.Errorf("can't convert %v to JSON.", value)
+}
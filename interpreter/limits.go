@@ -0,0 +1,68 @@
+package interpreter
+
+import (
+	goerrors "errors"
+	"friston/errors"
+)
+
+// ErrStepLimit is the cause of the errors.AbortError raised when a program runs more steps than MaxSteps.
+var ErrStepLimit = goerrors.New("step limit exceeded")
+
+// The context is checked for cancellation once per this many steps.
+const contextCheckInterval = 256
+
+// State shared by every copy of an interpreter during a run.
+type runState struct {
+	running bool
+	steps   int
+}
+
+// Starts a run from the host (ex: Execute), unless one is already running (ex: a host function calling back into friston).
+// The returned function ends the run.
+func (i Interpreter) startRun() func() {
+	if i.run.running {
+		return func() {}
+	}
+
+	i.run.running = true
+	i.run.steps = 0
+	return func() { i.run.running = false }
+}
+
+// Counts a step (a statement or loop iteration) against the step budget, and checks for cancellation.
+func (i Interpreter) step(line int) {
+	i.run.steps++
+
+	if i.MaxSteps > 0 && i.run.steps > i.MaxSteps {
+		i.abort(line, ErrStepLimit)
+	}
+
+	if i.Context != nil && i.run.steps%contextCheckInterval == 0 {
+		if err := i.Context.Err(); err != nil {
+			i.abort(line, err)
+		}
+	}
+}
+
+// Stops the program with an AbortError, which try statements don't catch.
+func (i Interpreter) abort(line int, cause error) {
+	panic(errors.AbortError{
+		RuntimeError: errors.RuntimeError{Line: line, Message: "Execution aborted: " + cause.Error() + ".", Trace: i.stackTrace(line)},
+		Cause:        cause,
+	})
+}
+
+// Recovers errors that stop a run, returning them as err, and passes any other panic on.
+func recoverRun(err *error) {
+	switch r := recover().(type) {
+	case nil:
+	case errors.RuntimeError:
+		*err = r
+	case errors.AbortError:
+		*err = r
+	case errors.ExitError:
+		*err = r
+	default:
+		panic(r)
+	}
+}
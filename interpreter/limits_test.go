@@ -0,0 +1,169 @@
+package interpreter
+
+import (
+	"context"
+	goerrors "errors"
+	"friston/ast"
+	"friston/errors"
+	"friston/lexer"
+	"friston/parser"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parse(t *testing.T, src string) []ast.Statement {
+	t.Helper()
+	lex := lexer.NewLexer(src, false)
+	tokens, lexErr := lex.ScanTokens()
+	if lexErr {
+		t.Fatalf("can't lex %q", src)
+	}
+	par := parser.NewParser(tokens)
+	stmts, parErr := par.Parse()
+	if parErr {
+		t.Fatalf("can't parse %q", src)
+	}
+	return stmts
+}
+
+func newTestInterpreter(allow Capabilities) Interpreter {
+	return NewInterpreter(false, ioutil.Discard, ioutil.Discard, strings.NewReader(""), allow)
+}
+
+// Returns the cause of an AbortError, failing if err isn't one.
+func abortCause(t *testing.T, err error) error {
+	t.Helper()
+	abort, ok := err.(errors.AbortError)
+	if !ok {
+		t.Fatalf("err = %#v, want an AbortError", err)
+	}
+	return abort.Cause
+}
+
+const forever = "let n = 0\nwhile true then\n    n = n + 1\n"
+
+func TestStepLimit(t *testing.T) {
+	inter := newTestInterpreter(0)
+	inter.MaxSteps = 1000
+
+	_, err := inter.Execute(parse(t, forever))
+	if cause := abortCause(t, err); cause != ErrStepLimit {
+		t.Fatalf("cause = %v, want ErrStepLimit", cause)
+	}
+	if !goerrors.Is(err, ErrStepLimit) {
+		t.Fatal("errors.Is(err, ErrStepLimit) = false")
+	}
+
+	// Each run has its own budget.
+	value, err := inter.Execute(parse(t, "let m = 0\nfor let k = 0; k < 100; k++ then\n    m = m + k\nm\n"))
+	if err != nil || value != 4950.0 {
+		t.Fatalf("second run = %v, %v, want 4950", value, err)
+	}
+}
+
+func TestStepLimitIsNotCaught(t *testing.T) {
+	inter := newTestInterpreter(0)
+	inter.MaxSteps = 1000
+
+	src := "let caught = false\n" +
+		"try then\n" +
+		"    while true then\n" +
+		"        caught = false\n" +
+		"catch e then\n" +
+		"    caught = true\n" +
+		"finally then\n" +
+		"    return 1\n"
+	_, err := inter.Execute(parse(t, src))
+	if cause := abortCause(t, err); cause != ErrStepLimit {
+		t.Fatalf("cause = %v, want ErrStepLimit", cause)
+	}
+	if caught, _ := inter.Lookup("caught"); caught != false {
+		t.Fatal("a try statement caught the abort")
+	}
+}
+
+func TestContextCancelled(t *testing.T) {
+	inter := newTestInterpreter(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inter.Context = ctx
+
+	_, err := inter.Execute(parse(t, forever))
+	if cause := abortCause(t, err); cause != context.Canceled {
+		t.Fatalf("cause = %v, want context.Canceled", cause)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	inter := newTestInterpreter(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	inter.Context = ctx
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := inter.Execute(parse(t, forever))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if cause := abortCause(t, err); cause != context.DeadlineExceeded {
+			t.Fatalf("cause = %v, want context.DeadlineExceeded", cause)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the program didn't stop at its deadline")
+	}
+}
+
+// A returned call is a tail call, which doesn't nest, so the recursion adds to its result.
+func TestCallDepth(t *testing.T) {
+	inter := newTestInterpreter(0)
+	inter.MaxCallDepth = 50
+
+	src := "function down: n =\n    return 1 + down(n + 1)\ndown(0)\n"
+	_, err := inter.Execute(parse(t, src))
+	runtimeErr, ok := err.(errors.RuntimeError)
+	if !ok || !strings.Contains(runtimeErr.Message, "exceeded 50 nested calls") {
+		t.Fatalf("err = %#v, want a stack overflow", err)
+	}
+}
+
+func TestCallDepthOfImports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "imports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each module imports the next, so the imports nest 5 deep.
+	for n := 1; n <= 5; n++ {
+		src := "let x = 1\n"
+		if n < 5 {
+			src = "import \"m" + strconv.Itoa(n+1) + "\"\n"
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "m"+strconv.Itoa(n)+".fn"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inter := newTestInterpreter(Filesystem)
+	inter.Dir = dir
+	inter.MaxCallDepth = 3
+	_, err = inter.Execute(parse(t, "import \"m1\"\n"))
+	runtimeErr, ok := err.(errors.RuntimeError)
+	if !ok || !strings.Contains(runtimeErr.Message, "exceeded 3 nested calls") {
+		t.Fatalf("err = %#v, want a stack overflow", err)
+	}
+
+	inter = newTestInterpreter(Filesystem)
+	inter.Dir = dir
+	if _, err := inter.Execute(parse(t, "import \"m1\"\n")); err != nil {
+		t.Fatalf("import with the default depth = %v", err)
+	}
+}
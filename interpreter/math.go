@@ -0,0 +1,158 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Math natives, built on Go's math package.
+func init() {
+	registerNatives(map[string]Native{
+		"sqrt":      {mathFunc{"sqrt", math.Sqrt}, Pure},
+		"abs":       {mathFunc{"abs", math.Abs}, Pure},
+		"floor":     {mathFunc{"floor", math.Floor}, Pure},
+		"ceil":      {mathFunc{"ceil", math.Ceil}, Pure},
+		"round":     {mathFunc{"round", math.Round}, Pure},
+		"sin":       {mathFunc{"sin", math.Sin}, Pure},
+		"cos":       {mathFunc{"cos", math.Cos}, Pure},
+		"tan":       {mathFunc{"tan", math.Tan}, Pure},
+		"asin":      {mathFunc{"asin", math.Asin}, Pure},
+		"acos":      {mathFunc{"acos", math.Acos}, Pure},
+		"atan":      {mathFunc{"atan", math.Atan}, Pure},
+		"exp":       {mathFunc{"exp", math.Exp}, Pure},
+		"log":       {mathFunc{"log", math.Log}, Pure},
+		"log10":     {mathFunc{"log10", math.Log10}, Pure},
+		"log2":      {mathFunc{"log2", math.Log2}, Pure},
+		"pow":       {mathFunc2{"pow", math.Pow}, Pure},
+		"atan2":     {mathFunc2{"atan2", math.Atan2}, Pure},
+		"min":       {mathFuncN{"min", math.Min}, Pure},
+		"max":       {mathFuncN{"max", math.Max}, Pure},
+		"random":    {randomNative{}, Pure},
+		"randomInt": {randomIntNative{}, Pure},
+		"seed":      {seedNative{}, Pure},
+	})
+}
+
+// Constants declared in every global scope, alongside the natives. They can't be assigned or declared again
+// in the global scope, though local variables can hide them.
+var Constants = map[string]interface{}{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// A math function of one number, ex: sqrt(x)
+type mathFunc struct {
+	name string
+	fn   func(float64) float64
+}
+
+func (m mathFunc) Arity() Arity { return Exactly(1) }
+
+func (m mathFunc) Call(i Interpreter, args []interface{}) interface{} {
+	x := i.numberArg(m.name, args, 0)
+
+	result := m.fn(x)
+	if math.IsNaN(result) && !math.IsNaN(x) {
+		i.Throw(fmt.Sprintf("%s() is undefined for %v.", m.name, x))
+	}
+	return result
+}
+
+func (m mathFunc) String() string {
+	return "<native fn " + m.name + ">"
+}
+
+// A math function of two numbers, ex: pow(x, y)
+type mathFunc2 struct {
+	name string
+	fn   func(float64, float64) float64
+}
+
+func (m mathFunc2) Arity() Arity { return Exactly(2) }
+
+func (m mathFunc2) Call(i Interpreter, args []interface{}) interface{} {
+	x := i.numberArg(m.name, args, 0)
+	y := i.numberArg(m.name, args, 1)
+
+	result := m.fn(x, y)
+	if math.IsNaN(result) && !math.IsNaN(x) && !math.IsNaN(y) {
+		i.Throw(fmt.Sprintf("%s() is undefined for %v and %v.", m.name, x, y))
+	}
+	return result
+}
+
+func (m mathFunc2) String() string {
+	return "<native fn " + m.name + ">"
+}
+
+// A math function of one or more numbers, folded pairwise, ex: max(a, b, c)
+type mathFuncN struct {
+	name string
+	fn   func(float64, float64) float64
+}
+
+func (m mathFuncN) Arity() Arity { return AtLeast(1) }
+
+func (m mathFuncN) Call(i Interpreter, args []interface{}) interface{} {
+	result := i.numberArg(m.name, args, 0)
+	for n := 1; n < len(args); n++ {
+		result = m.fn(result, i.numberArg(m.name, args, n))
+	}
+	return result
+}
+
+func (m mathFuncN) String() string {
+	return "<native fn " + m.name + ">"
+}
+
+// Each interpreter has its own random source, seeded with the time unless a program calls seed().
+func newRandom() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// Returns a random number from 0 up to, but not including, 1.
+type randomNative struct{}
+
+func (r randomNative) Arity() Arity { return Exactly(0) }
+
+func (r randomNative) Call(i Interpreter, args []interface{}) interface{} {
+	return i.random.Float64()
+}
+
+// randomInt(min, max) returns a random whole number from min to max, including max.
+type randomIntNative struct{}
+
+func (r randomIntNative) Arity() Arity { return Exactly(2) }
+
+func (r randomIntNative) Call(i Interpreter, args []interface{}) interface{} {
+	min := i.intArg("randomInt", args, 0)
+	max := i.intArg("randomInt", args, 1)
+	if min > max {
+		i.Throw(fmt.Sprintf("randomInt() min %d is greater than max %d.", min, max))
+	}
+
+	// max-min+1 overflows an int for very wide ranges, which are counted as unsigned instead.
+	span := uint64(max) - uint64(min)
+	if span < uint64(maxInt) {
+		return float64(min + i.random.Intn(int(span)+1))
+	}
+	for {
+		if n := i.random.Uint64(); n <= span {
+			return float64(min + int(n))
+		}
+	}
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// Seeds the random source, so the numbers that follow are always the same.
+type seedNative struct{}
+
+func (s seedNative) Arity() Arity { return Exactly(1) }
+
+func (s seedNative) Call(i Interpreter, args []interface{}) interface{} {
+	i.random.Seed(int64(i.intArg("seed", args, 0)))
+	return nil
+}
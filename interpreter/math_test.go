@@ -0,0 +1,72 @@
+package interpreter
+
+import (
+	"friston/errors"
+	"math"
+	"testing"
+)
+
+func TestRandomInt(t *testing.T) {
+	inter := newTestInterpreter(0)
+
+	tests := []struct {
+		min float64
+		max float64
+	}{
+		{1, 6},
+		{-3, -3},
+		{-4611686018427387904, 4611686018427387904},
+		{-9223372036854775808, 9223372036854774784},
+	}
+	for _, test := range tests {
+		for n := 0; n < 100; n++ {
+			value := randomIntNative{}.Call(inter, []interface{}{test.min, test.max}).(float64)
+			if value < test.min || value > test.max {
+				t.Fatalf("randomInt(%v, %v) = %v", test.min, test.max, value)
+			}
+		}
+	}
+
+	if _, err := inter.Execute(parse(t, "randomInt(-4611686018427387904, 4611686018427387904)")); err != nil {
+		t.Fatalf("randomInt of a wide range = %v", err)
+	}
+}
+
+func TestConstants(t *testing.T) {
+	errorTests := []struct {
+		src  string
+		want string
+	}{
+		{"pi = 3", "Can't assign to 'pi', it's a constant."},
+		{"function f: =\n    e = 1\nf()", "Can't assign to 'e', it's a constant."},
+		{"let e = 1", "Can't declare 'e', it's a constant."},
+		{"function pi: =\n    return 1", "Can't declare 'pi', it's a constant."},
+	}
+	for _, test := range errorTests {
+		_, err := newTestInterpreter(0).Execute(parse(t, test.src))
+		runtimeErr, ok := err.(errors.RuntimeError)
+		if !ok || runtimeErr.Message != test.want {
+			t.Errorf("Execute(%q) = %#v, want %q", test.src, err, test.want)
+		}
+	}
+
+	// Local variables can hide the constants without changing them.
+	src := "let caught = nil\n" +
+		"try then\n" +
+		"    throw \"failed\"\n" +
+		"catch e then\n" +
+		"    e = \"replaced\"\n" +
+		"    caught = e\n" +
+		"function area: pi =\n" +
+		"    pi = pi * 2\n" +
+		"    return pi\n" +
+		"[caught, area(1), pi, e]\n"
+	value, err := newTestInterpreter(0).Execute(parse(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"replaced", 2.0, math.Pi, math.E}
+	if got := value.(*List).Elements; len(got) != 4 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+		t.Fatalf("values = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package interpreter
+
+import (
+	"fmt"
+	"friston/ast"
+	"friston/environment"
+	"friston/lexer"
+	"friston/parser"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The namespace of an imported file. Its top-level declarations are read as properties, ex: util.clamp(x)
+type Module struct {
+	Name    string
+	Path    string
+	globals environment.Environment
+}
+
+func (m *Module) Get(name string) (interface{}, bool) {
+	value, ok := m.globals.Values[name]
+	return value, ok
+}
+
+func (m *Module) String() string {
+	return "<module " + m.Name + ">"
+}
+
+// Modules are only run once per interpreter, later imports of the same file share the cached module.
+type moduleCache struct {
+	loaded map[string]*Module
+	// Paths of the modules currently being imported, in order, to detect import cycles.
+	loading []string
+}
+
+func newModuleCache() *moduleCache {
+	return &moduleCache{loaded: make(map[string]*Module)}
+}
+
+func (i Interpreter) VisitImportStmt(stmt ast.ImportStmt) interface{} {
+	if !i.capabilities.Allows(Filesystem) {
+		i.runtimeError(stmt.Keyword, "Permission denied, import needs the filesystem capability.")
+	}
+
+	path, ok := i.resolveModule(stmt.Path.Literal.(string))
+	if !ok {
+		i.runtimeError(stmt.Path, fmt.Sprintf("Module '%s' not found.", stmt.Path.Literal))
+	}
+
+	module := i.loadModule(stmt, path)
+
+	name := module.Name
+	if stmt.Name.Lexeme != "" {
+		name = stmt.Name.Lexeme
+	} else if !isIdentifier(name) {
+		i.runtimeError(stmt.Path, fmt.Sprintf("Module name '%s' isn't an identifier, name it with 'as'.", name))
+	}
+
+	i.environment.Declare(name, module)
+	return nil
+}
+
+// Finds the file of an imported path, relative to the importing script, then each directory of the search path.
+// The ".fn" extension is optional.
+func (i Interpreter) resolveModule(path string) (string, bool) {
+	if filepath.Ext(path) == "" {
+		path += ".fn"
+	}
+
+	var candidates []string
+	if filepath.IsAbs(path) {
+		candidates = []string{path}
+	} else {
+		candidates = append(candidates, filepath.Join(i.Dir, path))
+		for _, dir := range i.SearchPath {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err == nil && !info.IsDir() {
+			abs, err := filepath.Abs(candidate)
+			if err != nil {
+				return candidate, true
+			}
+			return abs, true
+		}
+	}
+
+	return "", false
+}
+
+// Runs a module file in its own global scope, or returns it from the cache if it has already been imported.
+func (i Interpreter) loadModule(stmt ast.ImportStmt, path string) *Module {
+	if module, ok := i.modules.loaded[path]; ok {
+		return module
+	}
+
+	for n, loading := range i.modules.loading {
+		if loading == path {
+			cycle := append(i.modules.loading[n:], path)
+			for c := range cycle {
+				cycle[c] = filepath.Base(cycle[c])
+			}
+			i.runtimeError(stmt.Path, "Import cycle: "+strings.Join(cycle, " -> ")+".")
+		}
+	}
+
+	dat, err := ioutil.ReadFile(path)
+	if err != nil {
+		i.runtimeError(stmt.Path, fmt.Sprintf("Could not read module '%s': %s.", stmt.Path.Literal, err))
+	}
+
+	lex := lexer.NewLexer(string(dat), false)
+	lex.Errors = i.stderr
+	tokens, lexErr := lex.ScanTokens()
+
+	var stmts []ast.Statement
+	parErr := false
+	if !lexErr {
+		par := parser.NewParser(tokens)
+		par.Errors = i.stderr
+		stmts, parErr = par.Parse()
+	}
+
+	if lexErr || parErr {
+		i.runtimeError(stmt.Path, fmt.Sprintf("Could not parse module '%s'.", stmt.Path.Literal))
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	// Natives are declared in a parent scope, so the module's own scope only holds its declarations.
+	module := &Module{name, path, environment.NewEnclosed(i.newGlobals())}
+
+	i.modules.loading = append(i.modules.loading, path)
+	defer func() { i.modules.loading = i.modules.loading[:len(i.modules.loading)-1] }()
+
+	// The module runs as if it was called from the import, so errors in it are traced back to the import.
+	i.checkCallDepth(stmt.Keyword)
+	i.frames = append(i.frames, callFrame{"<module " + name + ">", filepath.Base(path), stmt.Keyword, i.environment})
+	i.inTry = false
+	i.globals = module.globals
+	i.environment = module.globals
+	i.file = filepath.Base(path)
+	i.Dir = filepath.Dir(path)
+
+	for _, s := range stmts {
+		i.execute(s)
+	}
+
+	i.modules.loaded[path] = module
+	return module
+}
+
+// Checks whether name can be used as a variable name.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for n, r := range name {
+		if !((r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || r == '_' || (n > 0 && r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
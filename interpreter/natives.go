@@ -2,39 +2,133 @@ package interpreter
 
 import (
 	"fmt"
-	"time"
+	"strings"
 )
 
-var Natives = map[string]Function{
-	"clock" : clockNative{},
-	"println" : printlnNative{},
-	"print" : printNative{},
+var Natives = map[string]Native{
+	"clock" : {clockNative{}, Clock},
+	"println" : {printlnNative{}, Console},
+	"print" : {printNative{}, Console},
+	"stacktrace" : {stacktraceNative{}, Pure},
 }
 
 // Returns Unix time in seconds.
 type clockNative struct{}
 
-func (c clockNative) Arity() int { return 0 }
+func (c clockNative) Arity() Arity { return Exactly(0) }
 
 func (c clockNative) Call(i Interpreter, args []interface{}) interface{} {
-	now := time.Now()
+	now := i.Time.Now()
 	return float64(now.UnixNano()) / 1000000000
 }
 
+// Prints its arguments separated by spaces, or the sep option, ex: print(a, b, sep: ", ")
 type printNative struct{}
 
-func (p printNative) Arity() int { return 1 }
+func (p printNative) Arity() Arity { return AtLeast(0) }
+
+func (p printNative) Options() []string { return []string{"sep"} }
 
 func (p printNative) Call(i Interpreter, args []interface{}) interface{} {
-	fmt.Print(args[0])
+	fmt.Fprint(i.stdout, i.joinArgs("print", args))
 	return nil
 }
 
 type printlnNative struct{}
 
-func (p printlnNative) Arity() int { return 1 }
+func (p printlnNative) Arity() Arity { return AtLeast(0) }
+
+func (p printlnNative) Options() []string { return []string{"sep"} }
 
 func (p printlnNative) Call(i Interpreter, args []interface{}) interface{} {
-	fmt.Println(args[0])
+	fmt.Fprintln(i.stdout, i.joinArgs("println", args))
 	return nil
-}
\ No newline at end of file
+}
+
+func (i Interpreter) joinArgs(native string, args []interface{}) string {
+	parts := make([]string, len(args))
+	for n, arg := range args {
+		parts[n] = fmt.Sprint(arg)
+	}
+	return strings.Join(parts, i.stringOption(native, "sep", " "))
+}
+
+// Returns the current call stack, one "at function (file:line)" frame per line.
+type stacktraceNative struct{}
+
+func (s stacktraceNative) Arity() Arity { return Exactly(0) }
+
+func (s stacktraceNative) Call(i Interpreter, args []interface{}) interface{} {
+	return formatTrace(i.stackTrace(i.callSite.Line))
+}
+
+// Argument helpers for natives, which raise a runtime error if an argument has the wrong type.
+
+func (i Interpreter) stringArg(native string, args []interface{}, n int) string {
+	s, ok := args[n].(string)
+	if !ok {
+		i.Throw(fmt.Sprintf("%s() argument %d must be a string.", native, n+1))
+	}
+	return s
+}
+
+func (i Interpreter) numberArg(native string, args []interface{}, n int) float64 {
+	number, ok := args[n].(float64)
+	if !ok {
+		i.Throw(fmt.Sprintf("%s() argument %d must be a number.", native, n+1))
+	}
+	return number
+}
+
+func (i Interpreter) intArg(native string, args []interface{}, n int) int {
+	number, ok := args[n].(float64)
+	if !ok || number != float64(int(number)) {
+		i.Throw(fmt.Sprintf("%s() argument %d must be a whole number.", native, n+1))
+	}
+	return int(number)
+}
+
+func (i Interpreter) listArg(native string, args []interface{}, n int) *List {
+	list, ok := args[n].(*List)
+	if !ok {
+		i.Throw(fmt.Sprintf("%s() argument %d must be a list.", native, n+1))
+	}
+	return list
+}
+
+func (i Interpreter) functionArg(native string, args []interface{}, n int) Function {
+	function, ok := args[n].(Function)
+	if !ok {
+		i.Throw(fmt.Sprintf("%s() argument %d must be a function.", native, n+1))
+	}
+	return function
+}
+
+// Calls a function passed to a native (ex: a callback), from the native's call site.
+func (i Interpreter) invoke(function Function, args ...interface{}) interface{} {
+	function, args = i.checkCall(i.callSite, function, args, nil)
+	return i.call(i.callSite, function, args, nil)
+}
+
+// Returns the value of a keyword option the native was called with, or fallback if it wasn't given.
+func (i Interpreter) Option(name string, fallback interface{}) interface{} {
+	if value, ok := i.options[name]; ok {
+		return value
+	}
+	return fallback
+}
+
+func (i Interpreter) stringOption(native string, name string, fallback string) string {
+	s, ok := i.Option(name, fallback).(string)
+	if !ok {
+		i.Throw(fmt.Sprintf("%s() option '%s' must be a string.", native, name))
+	}
+	return s
+}
+
+// Adds a group of natives (ex: the string functions) to Natives.
+func registerNatives(natives map[string]Native) {
+	for name, native := range natives {
+		Natives[name] = native
+	}
+}
@@ -0,0 +1,295 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// String natives. Lengths and indexes count characters, not bytes.
+func init() {
+	registerNatives(map[string]Native{
+		"len":        {lenNative{}, Pure},
+		"substr":     {substrNative{}, Pure},
+		"split":      {splitNative{}, Pure},
+		"join":       {joinNative{}, Pure},
+		"trim":       {trimNative{}, Pure},
+		"upper":      {upperNative{}, Pure},
+		"lower":      {lowerNative{}, Pure},
+		"replace":    {replaceNative{}, Pure},
+		"contains":   {containsNative{}, Pure},
+		"startsWith": {startsWithNative{}, Pure},
+		"indexOf":    {indexOfNative{}, Pure},
+		"repeat":     {repeatNative{}, Pure},
+		"format":     {formatNative{}, Pure},
+		"str":        {strNative{}, Pure},
+		"num":        {numNative{}, Pure},
+	})
+}
+
+// Returns the length of a string, list or map.
+type lenNative struct{}
+
+func (l lenNative) Arity() Arity { return Exactly(1) }
+
+func (l lenNative) Call(i Interpreter, args []interface{}) interface{} {
+	switch value := args[0].(type) {
+	case string:
+		return float64(utf8.RuneCountInString(value))
+	case *List:
+		return float64(len(value.Elements))
+	case *Map:
+		return float64(len(value.Entries))
+	}
+
+	i.Throw("len() argument must be a string, list or map.")
+	return nil
+}
+
+// substr(s, start, end) returns the characters of s from start up to, but not including, end (or the end of s).
+type substrNative struct{}
+
+func (s substrNative) Arity() Arity { return Between(2, 3) }
+
+func (s substrNative) Call(i Interpreter, args []interface{}) interface{} {
+	runes := []rune(i.stringArg("substr", args, 0))
+	start := i.intArg("substr", args, 1)
+	end := len(runes)
+	if len(args) > 2 {
+		end = i.intArg("substr", args, 2)
+	}
+
+	if start < 0 || end > len(runes) || start > end {
+		i.Throw(fmt.Sprintf("substr() range %d to %d is out of range for length %d.", start, end, len(runes)))
+	}
+	return string(runes[start:end])
+}
+
+// split(s, sep) returns a list of the parts of s between each sep.
+type splitNative struct{}
+
+func (s splitNative) Arity() Arity { return Exactly(2) }
+
+func (s splitNative) Call(i Interpreter, args []interface{}) interface{} {
+	parts := strings.Split(i.stringArg("split", args, 0), i.stringArg("split", args, 1))
+
+	list := &List{}
+	for _, part := range parts {
+		list.Elements = append(list.Elements, part)
+	}
+	return list
+}
+
+// join(list, sep) returns the elements of list, as they would be printed, separated by sep (or nothing).
+type joinNative struct{}
+
+func (j joinNative) Arity() Arity { return Between(1, 2) }
+
+func (j joinNative) Call(i Interpreter, args []interface{}) interface{} {
+	list := i.listArg("join", args, 0)
+	sep := ""
+	if len(args) > 1 {
+		sep = i.stringArg("join", args, 1)
+	}
+
+	var parts []string
+	for _, element := range list.Elements {
+		parts = append(parts, fmt.Sprintf("%v", element))
+	}
+	return strings.Join(parts, sep)
+}
+
+// Removes whitespace from both ends of a string.
+type trimNative struct{}
+
+func (t trimNative) Arity() Arity { return Exactly(1) }
+
+func (t trimNative) Call(i Interpreter, args []interface{}) interface{} {
+	return strings.TrimSpace(i.stringArg("trim", args, 0))
+}
+
+type upperNative struct{}
+
+func (u upperNative) Arity() Arity { return Exactly(1) }
+
+func (u upperNative) Call(i Interpreter, args []interface{}) interface{} {
+	return strings.ToUpper(i.stringArg("upper", args, 0))
+}
+
+type lowerNative struct{}
+
+func (l lowerNative) Arity() Arity { return Exactly(1) }
+
+func (l lowerNative) Call(i Interpreter, args []interface{}) interface{} {
+	return strings.ToLower(i.stringArg("lower", args, 0))
+}
+
+// replace(s, old, new) replaces every old in s with new.
+type replaceNative struct{}
+
+func (r replaceNative) Arity() Arity { return Exactly(3) }
+
+func (r replaceNative) Call(i Interpreter, args []interface{}) interface{} {
+	return strings.ReplaceAll(i.stringArg("replace", args, 0), i.stringArg("replace", args, 1), i.stringArg("replace", args, 2))
+}
+
+type containsNative struct{}
+
+func (c containsNative) Arity() Arity { return Exactly(2) }
+
+func (c containsNative) Call(i Interpreter, args []interface{}) interface{} {
+	return strings.Contains(i.stringArg("contains", args, 0), i.stringArg("contains", args, 1))
+}
+
+type startsWithNative struct{}
+
+func (s startsWithNative) Arity() Arity { return Exactly(2) }
+
+func (s startsWithNative) Call(i Interpreter, args []interface{}) interface{} {
+	return strings.HasPrefix(i.stringArg("startsWith", args, 0), i.stringArg("startsWith", args, 1))
+}
+
+// indexOf(s, sub) returns the index of the first sub in s, or -1 if s doesn't contain it.
+type indexOfNative struct{}
+
+func (x indexOfNative) Arity() Arity { return Exactly(2) }
+
+func (x indexOfNative) Call(i Interpreter, args []interface{}) interface{} {
+	s := i.stringArg("indexOf", args, 0)
+	index := strings.Index(s, i.stringArg("indexOf", args, 1))
+	if index < 0 {
+		return -1.0
+	}
+	return float64(utf8.RuneCountInString(s[:index]))
+}
+
+// repeat(s, n) returns s repeated n times.
+// Strings made by repeat() are at most this many bytes, so a program can't run the host out of memory.
+const maxRepeatLength = 1 << 28
+
+type repeatNative struct{}
+
+func (r repeatNative) Arity() Arity { return Exactly(2) }
+
+func (r repeatNative) Call(i Interpreter, args []interface{}) interface{} {
+	s := i.stringArg("repeat", args, 0)
+	count := i.intArg("repeat", args, 1)
+	if count < 0 {
+		i.Throw("repeat() count must not be negative.")
+	}
+	if len(s) > 0 && count > maxRepeatLength/len(s) {
+		i.Throw(fmt.Sprintf("repeat() result must not be longer than %d bytes.", maxRepeatLength))
+	}
+	return strings.Repeat(s, count)
+}
+
+// format(template, ...values) formats values with printf verbs, ex: format("%s: %.2f", name, total)
+type formatNative struct{}
+
+func (f formatNative) Arity() Arity { return AtLeast(1) }
+
+func (f formatNative) Call(i Interpreter, args []interface{}) interface{} {
+	template := i.stringArg("format", args, 0)
+
+	s, err := formatString(template, args[1:])
+	if err != nil {
+		i.Throw("format() " + err.Error())
+	}
+	return s
+}
+
+// Formats values with Go's printf verbs, converting friston numbers for integer verbs (ex: %d).
+func formatString(template string, values []interface{}) (string, error) {
+	var out strings.Builder
+	next := 0
+
+	for n := 0; n < len(template); n++ {
+		if template[n] != '%' {
+			out.WriteByte(template[n])
+			continue
+		}
+
+		// Read the flags, width and precision up to the verb, ex: "%-8.2f"
+		start := n
+		n++
+		for n < len(template) && strings.IndexByte("+-# 0123456789.", template[n]) >= 0 {
+			n++
+		}
+		if n >= len(template) {
+			return "", fmt.Errorf("template ends with an incomplete verb")
+		}
+
+		verb := template[start : n+1]
+		if template[n] == '%' {
+			out.WriteByte('%')
+			continue
+		}
+
+		if next >= len(values) {
+			return "", fmt.Errorf("has too few values for its verbs")
+		}
+		value := values[next]
+		next++
+
+		switch template[n] {
+		case 'd', 'x', 'X', 'o', 'b', 'c':
+			number, ok := value.(float64)
+			if !ok || number != float64(int64(number)) {
+				return "", fmt.Errorf("verb %s needs a whole number, but got %v", verb, value)
+			}
+			value = int64(number)
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			if _, ok := value.(float64); !ok {
+				return "", fmt.Errorf("verb %s needs a number, but got %v", verb, value)
+			}
+		case 't':
+			if _, ok := value.(bool); !ok {
+				return "", fmt.Errorf("verb %s needs a bool, but got %v", verb, value)
+			}
+		case 's', 'q', 'v':
+			if _, ok := value.(string); !ok {
+				value = fmt.Sprintf("%v", value)
+			}
+		default:
+			return "", fmt.Errorf("has unknown verb %s", verb)
+		}
+
+		out.WriteString(fmt.Sprintf(verb, value))
+	}
+
+	if next < len(values) {
+		return "", fmt.Errorf("has more values than verbs")
+	}
+	return out.String(), nil
+}
+
+// Converts any value to a string, as it would be printed.
+type strNative struct{}
+
+func (s strNative) Arity() Arity { return Exactly(1) }
+
+func (s strNative) Call(i Interpreter, args []interface{}) interface{} {
+	return fmt.Sprintf("%v", args[0])
+}
+
+// Converts a string to a number, ex: num(" 4.5 ") is 4.5
+type numNative struct{}
+
+func (n numNative) Arity() Arity { return Exactly(1) }
+
+func (n numNative) Call(i Interpreter, args []interface{}) interface{} {
+	switch value := args[0].(type) {
+	case float64:
+		return value
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			i.Throw(fmt.Sprintf("num() can't convert %q to a number.", value))
+		}
+		return number
+	}
+
+	i.Throw(fmt.Sprintf("num() can't convert %v to a number.", args[0]))
+	return nil
+}
@@ -0,0 +1,40 @@
+package interpreter
+
+import (
+	"friston/errors"
+	"strings"
+	"testing"
+)
+
+func TestRepeat(t *testing.T) {
+	inter := newTestInterpreter(0)
+	value, err := inter.Execute(parse(t, `repeat("ab", 3)`))
+	if err != nil || value != "ababab" {
+		t.Fatalf("repeat = %v, %v, want ababab", value, err)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"overflow", `repeat("ab", 4611686018427387904)`, "must not be longer"},
+		{"huge count", `repeat("ab", 1000000000000)`, "must not be longer"},
+		{"negative", `repeat("ab", -1)`, "must not be negative"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := inter.Execute(parse(t, test.src))
+			runtimeErr, ok := err.(errors.RuntimeError)
+			if !ok || !strings.Contains(runtimeErr.Message, test.want) {
+				t.Fatalf("err = %#v, want a runtime error containing %q", err, test.want)
+			}
+
+			// The error can be caught.
+			src := "let caught = false\ntry then\n    " + test.src + "\ncatch e then\n    caught = true\ncaught\n"
+			if value, err := inter.Execute(parse(t, src)); err != nil || value != true {
+				t.Fatalf("caught = %v, %v, want true", value, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package interpreter
+
+import (
+	"fmt"
+	"friston/errors"
+	"os"
+	"strings"
+)
+
+// Natives for the process: exiting needs the Process capability, and environment variables need Env.
+func init() {
+	registerNatives(map[string]Native{
+		"exit":   {exitNative{}, Process},
+		"getEnv": {getEnvNative{}, Env},
+		"setEnv": {setEnvNative{}, Env},
+		"env":    {envNative{}, Env},
+	})
+}
+
+// exit(code) stops the program, which exits with code (or 0). Finally branches still run, but try can't catch it.
+type exitNative struct{}
+
+func (e exitNative) Arity() Arity { return Between(0, 1) }
+
+func (e exitNative) Call(i Interpreter, args []interface{}) interface{} {
+	code := 0
+	if len(args) > 0 {
+		code = i.intArg("exit", args, 0)
+	}
+	panic(errors.ExitError{Code: code})
+}
+
+// getEnv(name) returns the value of an environment variable, or nil if it isn't set.
+type getEnvNative struct{}
+
+func (g getEnvNative) Arity() Arity { return Exactly(1) }
+
+func (g getEnvNative) Call(i Interpreter, args []interface{}) interface{} {
+	value, ok := os.LookupEnv(i.stringArg("getEnv", args, 0))
+	if !ok {
+		return nil
+	}
+	return value
+}
+
+// setEnv(name, value) sets an environment variable, which processes started later inherit. A value of nil unsets it.
+type setEnvNative struct{}
+
+func (s setEnvNative) Arity() Arity { return Exactly(2) }
+
+func (s setEnvNative) Call(i Interpreter, args []interface{}) interface{} {
+	name := i.stringArg("setEnv", args, 0)
+
+	var err error
+	if args[1] == nil {
+		err = os.Unsetenv(name)
+	} else {
+		err = os.Setenv(name, i.stringArg("setEnv", args, 1))
+	}
+	if err != nil {
+		i.Throw(fmt.Sprintf("setEnv() can't set '%s', %s.", name, err))
+	}
+	return nil
+}
+
+// env() returns a map of every environment variable.
+type envNative struct{}
+
+func (e envNative) Arity() Arity { return Exactly(0) }
+
+func (e envNative) Call(i Interpreter, args []interface{}) interface{} {
+	m := NewMap()
+	for _, variable := range os.Environ() {
+		if eq := strings.IndexByte(variable, '='); eq >= 0 {
+			m.Entries[variable[:eq]] = variable[eq+1:]
+		}
+	}
+	return m
+}
@@ -0,0 +1,324 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Time natives. Times are TimeValues, and durations are numbers of milliseconds.
+// Only reading the current time and sleeping need the Clock capability.
+func init() {
+	registerNatives(map[string]Native{
+		"now":        {nowNative{}, Clock},
+		"sleep":      {sleepNative{}, Clock},
+		"date":       {dateNative{}, Pure},
+		"fromUnix":   {fromUnixNative{}, Pure},
+		"formatTime": {formatTimeNative{}, Pure},
+		"parseTime":  {parseTimeNative{}, Pure},
+		"inZone":     {inZoneNative{}, Pure},
+		"addTime":    {addTimeNative{}, Pure},
+		"addDate":    {addDateNative{}, Pure},
+		"timeDiff":   {timeDiffNative{}, Pure},
+		"duration":   {durationNative{}, Pure},
+	})
+}
+
+// The source of the current time for clock(), now() and sleep(). Tests can replace an interpreter's
+// TimeSource with a FakeClock, so programs that use the time are deterministic.
+type TimeSource interface {
+	Now() time.Time
+	// Waits for d, or returns the context's error if it is done first.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// The real time.
+type SystemTime struct{}
+
+func (s SystemTime) Now() time.Time { return time.Now() }
+
+func (s SystemTime) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// A clock that only moves when it's advanced, or when a program sleeps (which returns straight away).
+type FakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+func (f *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.Advance(d)
+	return nil
+}
+
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// A point in time, in a time zone. Its components are read as properties, ex: t.year, t.weekday
+type TimeValue struct {
+	Time time.Time
+}
+
+func (t TimeValue) Get(name string) (interface{}, bool) {
+	switch name {
+	case "year":
+		return float64(t.Time.Year()), true
+	case "month":
+		return float64(t.Time.Month()), true
+	case "day":
+		return float64(t.Time.Day()), true
+	case "hour":
+		return float64(t.Time.Hour()), true
+	case "minute":
+		return float64(t.Time.Minute()), true
+	case "second":
+		return float64(t.Time.Second()), true
+	case "millisecond":
+		return float64(t.Time.Nanosecond() / int(time.Millisecond)), true
+	case "weekday":
+		return t.Time.Weekday().String(), true
+	case "yearDay":
+		return float64(t.Time.YearDay()), true
+	case "zone":
+		return t.Time.Location().String(), true
+	case "unix":
+		return float64(t.Time.UnixNano()) / float64(time.Second), true
+	}
+
+	return nil, false
+}
+
+func (t TimeValue) String() string {
+	return t.Time.Format(time.RFC3339Nano)
+}
+
+// Names that can be used instead of a Go reference layout, ex: formatTime(t, "date")
+var timeLayouts = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"date":     "2006-01-02",
+	"time":     "15:04:05",
+	"datetime": "2006-01-02 15:04:05",
+	"rfc1123":  time.RFC1123,
+	"kitchen":  time.Kitchen,
+}
+
+// Returns the layout argument n, or RFC 3339 if it wasn't given.
+func (i Interpreter) layoutArg(native string, args []interface{}, n int) string {
+	if len(args) <= n {
+		return time.RFC3339
+	}
+
+	layout := i.stringArg(native, args, n)
+	if named, ok := timeLayouts[strings.ToLower(layout)]; ok {
+		return named
+	}
+	return layout
+}
+
+func (i Interpreter) timeArg(native string, args []interface{}, n int) time.Time {
+	t, ok := args[n].(TimeValue)
+	if !ok {
+		i.Throw(fmt.Sprintf("%s() argument %d must be a time.", native, n+1))
+	}
+	return t.Time
+}
+
+// Loads a time zone by its IANA name (ex: "Europe/Paris"), or "UTC" or "Local".
+func (i Interpreter) zone(native string, name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		i.Throw(fmt.Sprintf("%s() unknown time zone '%s'.", native, name))
+	}
+	return location
+}
+
+// Returns the zone option, which defaults to UTC.
+func (i Interpreter) zoneOption(native string) *time.Location {
+	return i.zone(native, i.stringOption(native, "zone", "UTC"))
+}
+
+// Converts a number of milliseconds to a duration.
+func (i Interpreter) durationArg(native string, args []interface{}, n int) time.Duration {
+	ms := i.numberArg(native, args, n)
+	if math.IsNaN(ms) || math.IsInf(ms, 0) {
+		i.Throw(fmt.Sprintf("%s() argument %d must be a finite number.", native, n+1))
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// now() returns the current time, in the local time zone.
+type nowNative struct{}
+
+func (n nowNative) Arity() Arity { return Exactly(0) }
+
+func (n nowNative) Call(i Interpreter, args []interface{}) interface{} {
+	return TimeValue{i.Time.Now()}
+}
+
+// sleep(ms) waits for a number of milliseconds. Cancelling the interpreter's context stops the program while it sleeps.
+type sleepNative struct{}
+
+func (s sleepNative) Arity() Arity { return Exactly(1) }
+
+func (s sleepNative) Call(i Interpreter, args []interface{}) interface{} {
+	d := i.durationArg("sleep", args, 0)
+
+	ctx := i.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := i.Time.Sleep(ctx, d); err != nil {
+		i.abort(i.callSite.Line, err)
+	}
+	return nil
+}
+
+// date(year, month, day, hour, minute, second, zone: "UTC") returns the time of a date, where the time of day is optional.
+// Out of range components are normalized, ex: date(2024, 1, 32) is February 1st.
+type dateNative struct{}
+
+func (d dateNative) Arity() Arity { return Between(3, 6) }
+
+func (d dateNative) Options() []string { return []string{"zone"} }
+
+func (d dateNative) Call(i Interpreter, args []interface{}) interface{} {
+	var components [6]int
+	for n := range args {
+		components[n] = i.intArg("date", args, n)
+	}
+
+	t := time.Date(components[0], time.Month(components[1]), components[2], components[3], components[4], components[5], 0, i.zoneOption("date"))
+	return TimeValue{t}
+}
+
+// fromUnix(seconds) returns the time of a Unix timestamp, in UTC.
+type fromUnixNative struct{}
+
+func (f fromUnixNative) Arity() Arity { return Exactly(1) }
+
+func (f fromUnixNative) Call(i Interpreter, args []interface{}) interface{} {
+	seconds := i.numberArg("fromUnix", args, 0)
+	whole, fraction := math.Modf(seconds)
+	return TimeValue{time.Unix(int64(whole), int64(fraction*float64(time.Second))).UTC()}
+}
+
+// formatTime(t, layout) formats a time with a Go reference layout (ex: "Jan 2, 2006") or a named layout (ex: "date").
+// The layout defaults to RFC 3339.
+type formatTimeNative struct{}
+
+func (f formatTimeNative) Arity() Arity { return Between(1, 2) }
+
+func (f formatTimeNative) Call(i Interpreter, args []interface{}) interface{} {
+	t := i.timeArg("formatTime", args, 0)
+	return t.Format(i.layoutArg("formatTime", args, 1))
+}
+
+// parseTime(s, layout, zone: "UTC") parses a time with a layout, like formatTime.
+// The zone is used when s doesn't include one.
+type parseTimeNative struct{}
+
+func (p parseTimeNative) Arity() Arity { return Between(1, 2) }
+
+func (p parseTimeNative) Options() []string { return []string{"zone"} }
+
+func (p parseTimeNative) Call(i Interpreter, args []interface{}) interface{} {
+	s := i.stringArg("parseTime", args, 0)
+
+	t, err := time.ParseInLocation(i.layoutArg("parseTime", args, 1), s, i.zoneOption("parseTime"))
+	if err != nil {
+		i.Throw(fmt.Sprintf("parseTime() can't parse '%s', %s.", s, err))
+	}
+	return TimeValue{t}
+}
+
+// inZone(t, zone) returns the same time in another time zone, ex: inZone(t, "America/New_York")
+type inZoneNative struct{}
+
+func (z inZoneNative) Arity() Arity { return Exactly(2) }
+
+func (z inZoneNative) Call(i Interpreter, args []interface{}) interface{} {
+	t := i.timeArg("inZone", args, 0)
+	return TimeValue{t.In(i.zone("inZone", i.stringArg("inZone", args, 1)))}
+}
+
+// addTime(t, ms) returns the time a number of milliseconds after t (or before, if negative).
+type addTimeNative struct{}
+
+func (a addTimeNative) Arity() Arity { return Exactly(2) }
+
+func (a addTimeNative) Call(i Interpreter, args []interface{}) interface{} {
+	t := i.timeArg("addTime", args, 0)
+	return TimeValue{t.Add(i.durationArg("addTime", args, 1))}
+}
+
+// addDate(t, years, months, days) moves a time by calendar days in its time zone,
+// so the time of day stays the same across daylight saving changes.
+type addDateNative struct{}
+
+func (a addDateNative) Arity() Arity { return Exactly(4) }
+
+func (a addDateNative) Call(i Interpreter, args []interface{}) interface{} {
+	t := i.timeArg("addDate", args, 0)
+	years := i.intArg("addDate", args, 1)
+	months := i.intArg("addDate", args, 2)
+	days := i.intArg("addDate", args, 3)
+	return TimeValue{t.AddDate(years, months, days)}
+}
+
+// timeDiff(a, b) returns the number of milliseconds from b to a.
+type timeDiffNative struct{}
+
+func (t timeDiffNative) Arity() Arity { return Exactly(2) }
+
+func (t timeDiffNative) Call(i Interpreter, args []interface{}) interface{} {
+	a := i.timeArg("timeDiff", args, 0)
+	b := i.timeArg("timeDiff", args, 1)
+	return milliseconds(a.Sub(b))
+}
+
+// duration(s) returns the milliseconds in a duration like "1h30m" or "250ms".
+type durationNative struct{}
+
+func (d durationNative) Arity() Arity { return Exactly(1) }
+
+func (d durationNative) Call(i Interpreter, args []interface{}) interface{} {
+	s := i.stringArg("duration", args, 0)
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		i.Throw(fmt.Sprintf("duration() can't parse '%s'.", s))
+	}
+	return milliseconds(parsed)
+}
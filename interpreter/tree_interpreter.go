@@ -1,39 +1,166 @@
 package interpreter
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"friston/ast"
 	"friston/environment"
 	"friston/errors"
 	"friston/lexer"
+	"io"
+	"math/rand"
 	"reflect"
+	"sort"
 )
 
+// Default maximum number of nested function calls before a stack overflow error.
+const DefaultMaxCallDepth = 10000
+
 type Interpreter struct {
-	Repl        bool
-	globals     environment.Environment
-	environment environment.Environment
+	Repl         bool
+	MaxCallDepth int
+	Script       string
+	// Maximum number of steps (statements and loop iterations) in a run, or 0 for no limit.
+	MaxSteps int
+	// Cancelling the context stops a running program, or nil to never stop it.
+	Context context.Context
+	// Directory of the running script, which imports are resolved from first, or "" for the working directory.
+	Dir string
+	// Directories imports are resolved from when they're not found relative to Dir.
+	SearchPath []string
+	// Source of the current time, which can be replaced by a FakeClock.
+	Time TimeSource
+	// Called before each statement, or nil to run without a debugger.
+	Debugger     Debugger
+	globals      environment.Environment
+	environment  environment.Environment
+	frames       []callFrame
+	callSite     lexer.Token
+	options      map[string]interface{}
+	inTry        bool
+	stdout       io.Writer
+	stderr       io.Writer
+	stdin        *bufio.Reader
+	run          *runState
+	capabilities Capabilities
+	modules      *moduleCache
+	file         string
+	random       *rand.Rand
 }
 
-func NewInterpreter(repl bool) Interpreter {
+// Program output (ex: println) is written to stdout, and runtime errors to stderr. Input is read from stdin.
+// Only natives needing the allowed capabilities can be called, ex: Console | Clock.
+func NewInterpreter(repl bool, stdout io.Writer, stderr io.Writer, stdin io.Reader, allow Capabilities) Interpreter {
 	i := Interpreter{}
 	i.Repl = repl
-	// Define global scope envionment (parent = nil)
-	i.globals = environment.NewEnvironment()
-
-	// Declare all native functions in the global environment
-	for k, v := range Natives {
-		i.globals.Declare(k, v)
+	i.stdout = stdout
+	i.stderr = stderr
+	// A *bufio.Reader is used as it is, so it can be shared with other readers of the same input.
+	if reader, ok := stdin.(*bufio.Reader); ok {
+		i.stdin = reader
+	} else if stdin != nil {
+		i.stdin = bufio.NewReader(stdin)
 	}
+	i.MaxCallDepth = DefaultMaxCallDepth
+	i.run = &runState{}
+	i.modules = newModuleCache()
+	i.random = newRandom()
+	i.Time = SystemTime{}
+	i.Script = "<script>"
+	i.capabilities = allow
+	i.globals = i.newGlobals()
 
 	i.environment = i.globals
 	return i
 }
 
-func (i Interpreter) Interpret(stmts []ast.Statement) {
+// Creates a global scope environment (parent = nil) with all native functions declared.
+func (i Interpreter) newGlobals() environment.Environment {
+	globals := environment.NewEnvironment()
+
+	// Natives that aren't allowed raise permission errors.
+	for k, v := range Natives {
+		if i.capabilities.Allows(v.Needs) {
+			globals.Declare(k, v.Function)
+		} else {
+			globals.Declare(k, deniedNative{v, k})
+		}
+	}
+
+	for k, v := range Constants {
+		globals.Declare(k, v)
+	}
+
+	return globals
+}
+
+// Runs a program, reporting a runtime error that stops it to stderr. The error is also returned.
+func (i Interpreter) Interpret(stmts []ast.Statement) error {
+	_, err := i.Execute(stmts)
+	switch e := err.(type) {
+	case errors.RuntimeError:
+		errors.ReportRuntimeError(i.stderr, e)
+	case errors.AbortError:
+		errors.ReportRuntimeError(i.stderr, e.RuntimeError)
+	}
+	return err
+}
+
+// Executes statements in the global scope, returning the value of the last statement if it is an expression.
+// A runtime error stops execution, and is returned as an errors.RuntimeError,
+// or an errors.AbortError if the program ran out of steps or its context was cancelled.
+// A program that calls exit() returns an errors.ExitError.
+func (i Interpreter) Execute(stmts []ast.Statement) (value interface{}, err error) {
+	defer i.startRun()()
+	defer recoverRun(&err)
+
 	for _, s := range stmts {
-		i.execute(s)
+		value = i.execute(s)
+	}
+
+	// A return statement at the top level just ends with its value.
+	if ret, ok := value.(returnValue); ok {
+		value = ret.value
+	}
+	return value, nil
+}
+
+// Calls a function from outside of a running program (ex: from the host of an embedded interpreter).
+func (i Interpreter) CallFunction(function Function, args []interface{}) (value interface{}, err error) {
+	defer i.startRun()()
+	defer recoverRun(&err)
+
+	// There is no call site, so the call is checked against an empty token.
+	var paren lexer.Token
+	function, args = i.checkCall(paren, function, args, nil)
+	return i.call(paren, function, args, nil), nil
+}
+
+// Declares a variable in the global scope.
+func (i Interpreter) Define(name string, value interface{}) {
+	i.globals.Declare(name, value)
+}
+
+// Returns the names of the global variables (including natives), sorted.
+func (i Interpreter) Globals() []string {
+	var names []string
+	for name := range i.globals.Values {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the value of a global variable, and whether it is declared.
+func (i Interpreter) Lookup(name string) (interface{}, bool) {
+	value, ok := i.globals.Values[name]
+	return value, ok
+}
+
+// Raises a runtime error from a native function, at the line the native was called from.
+func (i Interpreter) Throw(message string) {
+	i.runtimeError(i.callSite, message)
 }
 
 // Helper methods:
@@ -43,34 +170,155 @@ func (i Interpreter) evaluate(expr ast.Expression) interface{} {
 }
 
 func (i Interpreter) execute(stmt ast.Statement) interface{} {
+	i.step(ast.Line(stmt))
+
 	block, ok := stmt.(ast.Block)
 	if ok {
 		return i.executeBlock(block)
 	}
 
+	if i.Debugger != nil {
+		i.Debugger.Before(i, stmt)
+	}
+
 	return stmt.Accept(i)
 }
 
 func (i Interpreter) executeBlock(block ast.Block) interface{} {
-	var value interface{} = nil
-
 	for _, stmt := range block.Stmts {
-		// Check if the current statement is a return, if so, return it's value
-		returnStmt, ok := stmt.(ast.ReturnStmt)
-		if ok {
-			return i.evaluate(returnStmt.Value)
+		// Stop at a return statement (including one in a nested statement), and pass its value up.
+		value := i.execute(stmt)
+		if _, ok := value.(returnValue); ok {
+			return value
 		}
+	}
+
+	return nil
+}
+
+// File of the code being run, which is the script unless an imported module is running.
+func (i Interpreter) currentFile() string {
+	if i.file != "" {
+		return i.file
+	}
+	return i.Script
+}
+
+// Stops execution with a runtime error, recording the friston call stack at the token that raised it.
+func (i Interpreter) runtimeError(token lexer.Token, message string) {
+	panic(errors.RuntimeError{Line: token.Line, Message: message, Trace: i.stackTrace(token.Line)})
+}
+
+// Builds a stack trace from the innermost call outwards, where line is the current line of the innermost call.
+func (i Interpreter) stackTrace(line int) []errors.Frame {
+	var trace []errors.Frame
+	for n := len(i.frames) - 1; n >= 0; n-- {
+		trace = append(trace, errors.Frame{Function: i.frames[n].name, File: i.frames[n].file, Line: line})
+		line = i.frames[n].call.Line
+	}
 
-		// If a block statement is found, it will return it's own return statement value, or nil
-		blockStmt, ok := stmt.(ast.Block)
-		if ok {
-			return i.executeBlock(blockStmt)
+	return append(trace, errors.Frame{Function: "<script>", File: i.Script, Line: line})
+}
+
+// Evaluates the callee, arguments and keyword arguments (or nil if there are none) of a call expression.
+func (i Interpreter) evaluateCall(c ast.Call) (interface{}, []interface{}, map[string]interface{}) {
+	// Callee should probably be an IDENTIFIER, but really it can be anything, almost.
+	callee := i.evaluate(c.Callee)
+
+	var arguments []interface{}
+	for _, arg := range c.Arguments {
+		arguments = append(arguments, i.evaluate(arg))
+	}
+
+	var keywords map[string]interface{}
+	for _, keyword := range c.Keywords {
+		if keywords == nil {
+			keywords = map[string]interface{}{}
 		}
+		keywords[keyword.Name.Lexeme] = i.evaluate(keyword.Value)
+	}
 
-		value = i.execute(stmt)
+	return callee, arguments, keywords
+}
+
+// Checks that callee can be called with the given arguments, and returns it as a Function.
+// Keyword arguments to user functions are matched to their parameters, and returned with the other arguments.
+func (i Interpreter) checkCall(paren lexer.Token, callee interface{}, arguments []interface{}, keywords map[string]interface{}) (Function, []interface{}) {
+	// Cast the callee to type callable.function, and call it if it is a callable type.
+	function, ok := callee.(Function)
+	if !ok {
+		i.runtimeError(paren, "Can only call functions.")
 	}
 
-	return value
+	if keywords != nil {
+		var err error
+		if user, ok := function.(UserFunc); ok {
+			arguments, err = user.keywordArgs(arguments, keywords)
+		} else {
+			err = checkOptions(function, keywords)
+		}
+		if err != nil {
+			i.runtimeError(paren, err.Error())
+		}
+	}
+
+	// Check function arity. (Number of arguments)
+	if !function.Arity().Accepts(len(arguments)) {
+		i.runtimeError(paren, fmt.Sprintf("Expected %v, but got %v.", function.Arity(), len(arguments)))
+	}
+
+	return function, arguments
+}
+
+// Calls a function, pushing a new frame onto the call stack for user functions.
+// Natives can read the options they're called with using Option.
+func (i Interpreter) call(paren lexer.Token, function Function, arguments []interface{}, options map[string]interface{}) interface{} {
+	if user, ok := function.(UserFunc); ok {
+		i.checkCallDepth(paren)
+		i.frames = append(i.frames, callFrame{user.Identifier.Lexeme, user.File, paren, i.environment})
+		i.inTry = false
+	}
+
+	// Natives raise their errors at the line they were called from.
+	i.callSite = paren
+	i.options = options
+	return function.Call(i, arguments)
+}
+
+// Raises a stack overflow error at token if another frame (a call or an import) would exceed MaxCallDepth.
+func (i Interpreter) checkCallDepth(token lexer.Token) {
+	if len(i.frames) >= i.MaxCallDepth {
+		i.runtimeError(token, fmt.Sprintf("Stack overflow, exceeded %d nested calls.", i.MaxCallDepth))
+	}
+}
+
+// Raises an error for assigning one of the Constants, or declaring one again in the global scope.
+// Local variables (ex: catch e) can hide them.
+func (i Interpreter) checkConstant(name lexer.Token, declare bool) {
+	if _, ok := Constants[name.Lexeme]; !ok {
+		return
+	}
+
+	// The scope a name is declared in is the current one, and the one it's assigned in is the nearest that has it.
+	env := i.environment
+	for !declare {
+		if _, ok := env.Values[name.Lexeme]; ok {
+			break
+		}
+		parent, ok := env.GetParent().(environment.Environment)
+		if !ok {
+			break
+		}
+		env = parent
+	}
+
+	if _, local := env.GetParent().(environment.Environment); local {
+		return
+	}
+	if declare {
+		i.runtimeError(name, fmt.Sprintf("Can't declare '%s', it's a constant.", name.Lexeme))
+	}
+	i.runtimeError(name, fmt.Sprintf("Can't assign to '%s', it's a constant.", name.Lexeme))
 }
 
 // Nil, false bools, zero, empty strings are false, all else is true.
@@ -98,7 +346,9 @@ func isTruth(expr interface{}) bool {
 	return true
 }
 
-// Nil is only equal to itself (our equality differs from Golang).
+// Nil is only equal to itself (our equality differs from Golang). Caught errors are equal if their messages,
+// lines and values are, and functions if they're the same declaration with the same closure. Other values Go
+// can't compare (ex: natives holding a Go func) are never equal.
 func isEqual(left interface{}, right interface{}) bool {
 	if left == nil && right == nil {
 		return true
@@ -106,31 +356,39 @@ func isEqual(left interface{}, right interface{}) bool {
 		return false
 	}
 
+	switch l := left.(type) {
+	case ErrorValue:
+		r, ok := right.(ErrorValue)
+		return ok && l.Message == r.Message && l.Line == r.Line && isEqual(l.Value, r.Value)
+	case UserFunc:
+		r, ok := right.(UserFunc)
+		return ok && l.Identifier == r.Identifier && reflect.ValueOf(l.Closure.Values).Pointer() == reflect.ValueOf(r.Closure.Values).Pointer()
+	}
+
+	// Comparing two values of the same uncomparable type panics in Go.
+	if reflect.TypeOf(left) == reflect.TypeOf(right) && !reflect.TypeOf(left).Comparable() {
+		return false
+	}
 	return left == right
 }
 
-func checkNumberOperand(operator lexer.Token, number interface{}) bool {
+func (i Interpreter) checkNumberOperand(operator lexer.Token, number interface{}) {
 	switch number.(type) {
 	case int, float64:
-		return true
 	default:
-		errors.ThrowError(operator.Line, "Operand must be a number.")
-		return false
+		i.runtimeError(operator, "Operand must be a number.")
 	}
 }
 
-func checkNumberOperands(operator lexer.Token, left interface{}, right interface{}) bool {
+func (i Interpreter) checkNumberOperands(operator lexer.Token, left interface{}, right interface{}) {
 	if reflect.TypeOf(left) == reflect.TypeOf(right) {
 		switch left.(type) {
 		case int, float64:
-			return true
 		default:
-			errors.ThrowError(operator.Line, "Operands must be a number.")
-			return false
+			i.runtimeError(operator, "Operands must be a number.")
 		}
 	} else {
-		errors.ThrowError(operator.Line, "Operand types must match.")
-		return false
+		i.runtimeError(operator, "Operand types must match.")
 	}
 }
 
@@ -144,50 +402,43 @@ func (i Interpreter) VisitBinary(b ast.Binary) interface{} {
 	switch b.Op.TType {
 	// Basic arithmetic:
 	case lexer.MINUS:
-		if checkNumberOperands(b.Op, left, right) {
-			return left.(float64) - right.(float64)
-		}
+		i.checkNumberOperands(b.Op, left, right)
+		return left.(float64) - right.(float64)
 	case lexer.STAR:
-		if checkNumberOperands(b.Op, left, right) {
-			return left.(float64) * right.(float64)
-		}
+		i.checkNumberOperands(b.Op, left, right)
+		return left.(float64) * right.(float64)
 	case lexer.SLASH:
-		if checkNumberOperands(b.Op, left, right) {
-			if right.(float64) == 0 {
-				errors.ThrowError(b.Op.Line, "Division by zero.")
-				return nil
-			}
-			return left.(float64) / right.(float64)
+		i.checkNumberOperands(b.Op, left, right)
+		if right.(float64) == 0 {
+			i.runtimeError(b.Op, "Division by zero.")
 		}
+		return left.(float64) / right.(float64)
 
 	// Addition (includes string concatenation):
 	case lexer.PLUS:
 		switch left.(type) {
 		case int, float64:
-			if checkNumberOperands(b.Op, left, right) {
-				return left.(float64) + right.(float64)
-			}
+			i.checkNumberOperands(b.Op, left, right)
+			return left.(float64) + right.(float64)
 		case string:
 			return fmt.Sprintf("%v%v", left, right)
+		default:
+			i.runtimeError(b.Op, "Operands must be numbers, or start with a string.")
 		}
 
 	// Comparisons:
 	case lexer.GREATER:
-		if checkNumberOperands(b.Op, left, right) {
-			return left.(float64) > right.(float64)
-		}
+		i.checkNumberOperands(b.Op, left, right)
+		return left.(float64) > right.(float64)
 	case lexer.GREATER_EQUAL:
-		if checkNumberOperands(b.Op, left, right) {
-			return left.(float64) >= right.(float64)
-		}
+		i.checkNumberOperands(b.Op, left, right)
+		return left.(float64) >= right.(float64)
 	case lexer.LESS:
-		if checkNumberOperands(b.Op, left, right) {
-			return left.(float64) < right.(float64)
-		}
+		i.checkNumberOperands(b.Op, left, right)
+		return left.(float64) < right.(float64)
 	case lexer.LESS_EQUAL:
-		if checkNumberOperands(b.Op, left, right) {
-			return left.(float64) <= right.(float64)
-		}
+		i.checkNumberOperands(b.Op, left, right)
+		return left.(float64) <= right.(float64)
 	case lexer.EQUAL_EQUAL:
 		return isEqual(left, right)
 	case lexer.BANG_EQUAL:
@@ -223,9 +474,8 @@ func (i Interpreter) VisitUnary(u ast.Unary) interface{} {
 
 	switch u.Op.TType {
 	case lexer.MINUS:
-		if checkNumberOperand(u.Op, right) {
-			return -right.(float64)
-		}
+		i.checkNumberOperand(u.Op, right)
+		return -right.(float64)
 	case lexer.BANG:
 		return !isTruth(right)
 	}
@@ -243,52 +493,96 @@ func (i Interpreter) VisitLiteral(l ast.Literal) interface{} {
 }
 
 func (i Interpreter) VisitVariable(vr ast.Variable) interface{} {
-	return i.environment.Get(vr.Name)
+	value, ok := i.environment.Get(vr.Name)
+	if !ok {
+		i.runtimeError(vr.Name, fmt.Sprintf("Undefined variable '%s'.", vr.Name.Lexeme))
+	}
+	return value
 }
 
 func (i Interpreter) VisitAssignment(a ast.Assignment) interface{} {
 	value := i.evaluate(a.Value)
 
-	i.environment.Assign(a.Name, value)
+	i.checkConstant(a.Name, false)
+	if !i.environment.Assign(a.Name, value) {
+		i.runtimeError(a.Name, fmt.Sprintf("Undefined variable '%s'.", a.Name.Lexeme))
+	}
 	return value
 }
 
-func (i Interpreter) VisitCall(c ast.Call) interface{} {
-	// Callee should probably be an IDENTIFIER, but really it can be anything, almost.
-	callee := i.evaluate(c.Callee)
-
-	var arguments []interface{}
-	for _, arg := range c.Arguments {
-		arguments = append(arguments, i.evaluate(arg))
+func (i Interpreter) VisitGet(g ast.Get) interface{} {
+	object, ok := i.evaluate(g.Object).(Object)
+	if !ok {
+		i.runtimeError(g.Name, "Only objects have properties.")
 	}
 
-	// Cast the callee to type callable.function, and call it if it is a callable type.
-	function, ok := callee.(Function)
+	value, ok := object.Get(g.Name.Lexeme)
 	if !ok {
-		// TODO: Runtime errors!
-		errors.ThrowError(c.Paren.Line, "Can only call functions.")
+		i.runtimeError(g.Name, fmt.Sprintf("Undefined property '%s'.", g.Name.Lexeme))
 	}
+	return value
+}
 
-	// Check function arity. (Number of arguments)
-	if len(arguments) != function.Arity() {
-		errors.ThrowError(c.Paren.Line, fmt.Sprintf("Expected %v, but got %v arguments.", function.Arity(), len(arguments)))
-	} else {
-		value := function.Call(i, arguments)
-		return value
+func (i Interpreter) VisitIndex(ix ast.Index) interface{} {
+	object := i.evaluate(ix.Object)
+	index := i.evaluate(ix.Index)
+
+	switch object := object.(type) {
+	case *List:
+		return object.Elements[i.checkIndex(ix.Bracket, index, len(object.Elements))]
+	case string:
+		runes := []rune(object)
+		return string(runes[i.checkIndex(ix.Bracket, index, len(runes))])
+	case *Map:
+		key, ok := index.(string)
+		if !ok {
+			i.runtimeError(ix.Bracket, "Map keys must be strings.")
+		}
+		return object.Entries[key]
 	}
 
+	i.runtimeError(ix.Bracket, "Only lists, strings and maps can be indexed.")
 	return nil
 }
 
+// Checks that index is a whole number within a list (or string) of the given length.
+func (i Interpreter) checkIndex(bracket lexer.Token, index interface{}, length int) int {
+	number, ok := index.(float64)
+	if !ok || number != float64(int(number)) {
+		i.runtimeError(bracket, "Index must be a whole number.")
+	}
+
+	if number < 0 || int(number) >= length {
+		i.runtimeError(bracket, fmt.Sprintf("Index %v out of range for length %d.", number, length))
+	}
+	return int(number)
+}
+
+func (i Interpreter) VisitListLiteral(l ast.ListLiteral) interface{} {
+	list := &List{}
+	for _, element := range l.Elements {
+		list.Elements = append(list.Elements, i.evaluate(element))
+	}
+	return list
+}
+
+func (i Interpreter) VisitCall(c ast.Call) interface{} {
+	callee, arguments, keywords := i.evaluateCall(c)
+
+	function, arguments := i.checkCall(c.Paren, callee, arguments, keywords)
+	return i.call(c.Paren, function, arguments, keywords)
+}
+
 // Statement Visitor methods:
 
 func (i Interpreter) VisitExprStmt(e ast.ExprStmt) interface{} {
 	value := i.evaluate(e.Expr)
 
-	if i.Repl {
-		fmt.Printf("%v\n", value)
+	// Calls with no value (ex: println) don't print nil.
+	if i.Repl && value != nil {
+		fmt.Fprintf(i.stdout, "%v\n", value)
 	}
-	return nil
+	return value
 }
 
 func (i Interpreter) VisitIfStmt(stmt ast.IfStmt) interface{} {
@@ -302,7 +596,29 @@ func (i Interpreter) VisitIfStmt(stmt ast.IfStmt) interface{} {
 
 func (i Interpreter) VisitWhileStmt(stmt ast.WhileStmt) interface{} {
 	for isTruth(i.evaluate(stmt.Condition)) {
-		i.execute(stmt.LoopBranch)
+		i.step(ast.Line(stmt))
+
+		value := i.execute(stmt.LoopBranch)
+		if _, ok := value.(returnValue); ok {
+			return value
+		}
+	}
+	return nil
+}
+
+// The for loop's declaration is scoped to the loop, and the increment runs after each iteration.
+func (i Interpreter) VisitForStmt(stmt ast.ForStmt) interface{} {
+	i.environment = environment.NewEnclosed(i.environment)
+	i.execute(stmt.Initializer)
+
+	for isTruth(i.evaluate(stmt.Condition)) {
+		i.step(ast.Line(stmt))
+
+		value := i.execute(stmt.LoopBranch)
+		if _, ok := value.(returnValue); ok {
+			return value
+		}
+		i.evaluate(stmt.Increment)
 	}
 	return nil
 }
@@ -313,13 +629,14 @@ func (i Interpreter) VisitFuncDecl(f ast.FuncDecl) interface{} {
 		if param.TType == lexer.IDENTIFIER {
 			parameters = append(parameters, param.Lexeme)
 		} else {
-			errors.ThrowError(param.Line, "Parameters must be identifiers.")
+			i.runtimeError(param, "Parameters must be identifiers.")
 		}
 	}
 
 	// Capture the current environment when defining a function.
-	function := UserFunc{f.Name, parameters, f.Block, i.environment}
+	function := UserFunc{f.Name, parameters, f.Defaults, f.Rest, f.Block, i.environment, i.currentFile()}
 
+	i.checkConstant(f.Name, true)
 	i.environment.Declare(f.Name.Lexeme, function)
 	return nil
 }
@@ -330,20 +647,99 @@ func (i Interpreter) VisitVarDecl(d ast.VarDecl) interface{} {
 		value = i.evaluate(d.Initializer)
 	}
 
+	i.checkConstant(d.Name, true)
 	i.environment.Declare(d.Name.Lexeme, value)
 	return nil
 }
 
 func (i Interpreter) VisitReturn(r ast.ReturnStmt) interface{} {
-	return i.evaluate(r.Value)
+	if r.Value == nil {
+		return returnValue{nil}
+	}
+
+	// Inside a function, a returned call to a user function is a tail call, and is run by the caller's UserFunc.Call.
+	// Calls inside a try statement are not tail calls, as their errors must be caught by it.
+	c, ok := r.Value.(ast.Call)
+	if ok && len(i.frames) > 0 && !i.inTry {
+		callee, arguments, keywords := i.evaluateCall(c)
+
+		function, arguments := i.checkCall(c.Paren, callee, arguments, keywords)
+		if user, ok := function.(UserFunc); ok {
+			return returnValue{tailCall{user, arguments}}
+		}
+		return returnValue{i.call(c.Paren, function, arguments, keywords)}
+	}
+
+	return returnValue{i.evaluate(r.Value)}
+}
+
+func (i Interpreter) VisitTryStmt(stmt ast.TryStmt) (value interface{}) {
+	// The finally branch runs however the try statement ends. As in JavaScript, a return or throw from it
+	// replaces the try statement's value, or the error it raised.
+	if stmt.FinallyBranch != nil {
+		defer func() {
+			r := recover()
+			if _, ok := r.(errors.RuntimeError); r != nil && !ok {
+				// exit() and aborted runs can't be caught, so they carry on however the finally branch ends.
+				defer panic(r)
+				i.execute(stmt.FinallyBranch)
+				return
+			}
+
+			finally := i.execute(stmt.FinallyBranch)
+			if _, ok := finally.(returnValue); ok {
+				value = finally
+			} else if r != nil {
+				panic(r)
+			}
+		}()
+	}
+
+	try := i
+	try.inTry = true
+	if stmt.CatchBranch == nil {
+		return try.execute(stmt.TryBranch)
+	}
+	return try.tryCatch(stmt)
+}
+
+// Executes the try branch, and the catch branch if it raises a runtime error.
+func (i Interpreter) tryCatch(stmt ast.TryStmt) (value interface{}) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		err, ok := r.(errors.RuntimeError)
+		if !ok {
+			panic(r)
+		}
+
+		// The caught error is only declared within the catch branch.
+		i.environment = environment.NewEnclosed(i.environment)
+		if stmt.CatchName.Lexeme != "" {
+			i.environment.Declare(stmt.CatchName.Lexeme, newErrorValue(err))
+		}
+		value = i.execute(stmt.CatchBranch)
+	}()
+
+	return i.execute(stmt.TryBranch)
+}
+
+func (i Interpreter) VisitThrowStmt(stmt ast.ThrowStmt) interface{} {
+	value := i.evaluate(stmt.Value)
+
+	// Caught errors are thrown again unchanged, keeping their original line and trace.
+	if err, ok := value.(ErrorValue); ok {
+		panic(err.runtimeError())
+	}
+
+	panic(errors.RuntimeError{Line: stmt.Keyword.Line, Message: fmt.Sprintf("%v", value), Trace: i.stackTrace(stmt.Keyword.Line), Value: value})
 }
 
 func (i Interpreter) VisitBlock(b ast.Block) interface{} {
 	// Create a new environment, enclosed by the current scope, and set the current environment to it.
 	i.environment = environment.NewEnclosed(i.environment)
-
-	fmt.Println(i.environment.GetParent())
-	fmt.Println(i.environment.Values)
-
 	return i.executeBlock(b)
 }
@@ -0,0 +1,108 @@
+package interpreter
+
+import (
+	"fmt"
+	"friston/errors"
+	"sort"
+	"strings"
+)
+
+// Values with named properties, read with the '.' operator.
+type Object interface {
+	Get(name string) (interface{}, bool)
+}
+
+// A caught runtime error, bound to the variable of a catch branch.
+type ErrorValue struct {
+	Message string
+	Line    int
+	Trace   []errors.Frame
+	Value   interface{}
+}
+
+func newErrorValue(err errors.RuntimeError) ErrorValue {
+	return ErrorValue{err.Message, err.Line, err.Trace, err.Value}
+}
+
+// Converts the error back into a RuntimeError, so it can be thrown again.
+func (e ErrorValue) runtimeError() errors.RuntimeError {
+	return errors.RuntimeError{Line: e.Line, Message: e.Message, Trace: e.Trace, Value: e.Value}
+}
+
+func (e ErrorValue) Get(name string) (interface{}, bool) {
+	switch name {
+	case "message":
+		return e.Message, true
+	case "line":
+		return float64(e.Line), true
+	case "trace":
+		return formatTrace(e.Trace), true
+	case "value":
+		return e.Value, true
+	}
+
+	return nil, false
+}
+
+func (e ErrorValue) String() string {
+	return "<error " + e.Message + ">"
+}
+
+// Formats a stack trace as one "at function (file:line)" frame per line.
+func formatTrace(trace []errors.Frame) string {
+	var frames []string
+	for _, frame := range trace {
+		frames = append(frames, frame.String())
+	}
+	return strings.Join(frames, "\n")
+}
+
+// An ordered list of values. Lists are shared by reference, like functions.
+type List struct {
+	Elements []interface{}
+}
+
+func (l *List) String() string {
+	var elements []string
+	for _, element := range l.Elements {
+		elements = append(elements, formatElement(element))
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// A map from string keys to values. Entries can be read as properties, ex: config.name
+type Map struct {
+	Entries map[string]interface{}
+}
+
+func NewMap() *Map {
+	return &Map{make(map[string]interface{})}
+}
+
+func (m *Map) Get(name string) (interface{}, bool) {
+	value, ok := m.Entries[name]
+	return value, ok
+}
+
+// Maps are printed with sorted keys, so their output is stable.
+func (m *Map) String() string {
+	var keys []string
+	for key := range m.Entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var entries []string
+	for _, key := range keys {
+		entries = append(entries, fmt.Sprintf("%q: %s", key, formatElement(m.Entries[key])))
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// Formats a value inside a list or map, where strings are quoted.
+func formatElement(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", value)
+}
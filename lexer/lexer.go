@@ -3,7 +3,10 @@ package lexer
 import (
 	"fmt"
 	"friston/errors"
+	"io"
+	"os"
 	"strconv"
+	"strings"
 )
 
 // Simple helper functions to avoid importing a whole module for a one-liner
@@ -17,11 +20,13 @@ func isAlpha(r rune) bool {
 }
 
 // Literals stores as empty interface, use type assertions when parsing
+// Column is the position of the token's first character in its line, starting at 1.
 type Token struct {
 	TType   TokenType
 	Lexeme  string
 	Literal interface{}
 	Line    int
+	Column  int
 }
 
 // Print an instance of a token.
@@ -49,6 +54,17 @@ type lexer struct {
 	hadError bool
 	repl     bool
 	depth    int
+	// Number of open brackets, inside which new lines don't end statements.
+	brackets int
+	// Index in source of the first character of the current line.
+	lineStart int
+	// Destination of lexing errors, defaults to os.Stderr so they stay out of program output. They are also
+	// collected in SyntaxErrors, so callers can set it to ioutil.Discard and report them their own way.
+	Errors io.Writer
+	// Every error reported, with its position.
+	SyntaxErrors []errors.SyntaxError
+	// Keep comments as COMMENT tokens (ex: for the formatter). The parser doesn't accept them.
+	Comments bool
 }
 
 // Lexer constructor, initializes default values
@@ -61,14 +77,22 @@ func NewLexer(code string, replFlag bool) lexer {
 	l.hadError = false
 	l.repl = replFlag
 	l.depth = 0
+	l.Errors = os.Stderr
 
 	return l
 }
 
 // Error handling:
 func (l *lexer) throwError(message string) {
-	errors.ThrowError(l.line, message)
+	errors.ThrowError(l.Errors, l.line, message)
 	l.hadError = true
+
+	// A token that spans lines (ex: a string) is reported at the start of the line it ends on.
+	column := 1
+	if l.start >= l.lineStart {
+		column = l.start - l.lineStart + 1
+	}
+	l.SyntaxErrors = append(l.SyntaxErrors, errors.SyntaxError{Line: l.line, Column: column, Message: message})
 }
 
 // Checks if current position has reaced the end of the source
@@ -112,23 +136,23 @@ func (l *lexer) match(x rune, a TokenType, b TokenType) TokenType {
 
 // Adds a new Token instance to l.tokens using input type and literal, and infered lexeme and line
 func (l *lexer) addToken(tType TokenType, literal interface{}) {
+	column := l.start - l.lineStart + 1
 	if l.repl == true {
-		l.tokens = append(l.tokens, Token{tType, l.source[l.start:l.current], literal, 0})
+		l.tokens = append(l.tokens, Token{tType, l.source[l.start:l.current], literal, 0, column})
 	} else {
-		l.tokens = append(l.tokens, Token{tType, l.source[l.start:l.current], literal, l.line})
+		l.tokens = append(l.tokens, Token{tType, l.source[l.start:l.current], literal, l.line, column})
 	}
 }
 
 // Consumes a string literal, including new lines, and creates a STRING token
 func (l *lexer) getString() {
+	line, lineStart := l.line, l.lineStart
+
+	// Advance through all characters until reaching the terminating ", counting lines in multi-line strings
 	for l.peek() != '"' && !l.isAtEnd() {
-		// Handle multi-line comments
-		if l.peek() == '\n' {
-			l.line++
-			l.advance()
+		if l.advance() == '\n' {
+			l.newLine()
 		}
-		// Advance through all characters until reaching the terminating "
-		l.advance()
 	}
 
 	// If we haven't reached the end of l.source, but find terminating "
@@ -139,7 +163,11 @@ func (l *lexer) getString() {
 		l.addToken(STRING, literal)
 		// If there's no terminating ", throw an error
 	} else {
+		// Report the error where the string starts, rather than at the end of the source.
+		endLine, endStart := l.line, l.lineStart
+		l.line, l.lineStart = line, lineStart
 		l.throwError("Unterminated string")
+		l.line, l.lineStart = endLine, endStart
 	}
 }
 
@@ -168,8 +196,8 @@ func (l *lexer) getNumber() {
 }
 
 func (l *lexer) getWord() {
-	// Advance to en of word
-	for isAlpha(l.peek()) && !l.isAtEnd() {
+	// Advance to en of word (digits are allowed after the first character, ex: log10)
+	for (isAlpha(l.peek()) || isDigit(l.peek())) && !l.isAtEnd() {
 		l.advance()
 	}
 
@@ -215,11 +243,11 @@ func (l *lexer) getDent() {
 
 	if difference > 0 {
 		for i := 0; i < difference; i++ {
-			l.tokens = append(l.tokens, Token{INDENT, "", nil, l.line})
+			l.tokens = append(l.tokens, Token{INDENT, "", nil, l.line, 1})
 		}
 	} else if difference < 0 {
 		for i := 0; i < -difference; i++ {
-			l.tokens = append(l.tokens, Token{DEDENT, "", nil, l.line})
+			l.tokens = append(l.tokens, Token{DEDENT, "", nil, l.line, 1})
 		}
 	}
 
@@ -228,12 +256,37 @@ func (l *lexer) getDent() {
 }
 
 func (l *lexer) getNewline() {
-	previousToken := l.tokens[len(l.tokens)-1]
+	// Comments don't end statements, so the NEWLINE depends on the token before them.
+	last := len(l.tokens) - 1
+	for last >= 0 && l.tokens[last].TType == COMMENT {
+		last--
+	}
+
+	// Blank source has no statement to end.
+	if last < 0 {
+		return
+	}
+	previousToken := l.tokens[last]
 
 	// Only append NEWLINE if the previous character is not a newline or 'then' keyword
 	if previousToken.TType != NEWLINE && previousToken.TType != SEMICOLON && previousToken.TType != THEN && previousToken.TType != EQUAL && previousToken.TType != DEDENT {
-		l.tokens = append(l.tokens, Token{NEWLINE, "", nil, l.line})
+		l.tokens = append(l.tokens, Token{NEWLINE, "", nil, l.line, l.current - l.lineStart + 1})
+	}
+}
+
+// Moves to the next line, after its '\n' has been consumed.
+func (l *lexer) newLine() {
+	l.line++
+	l.lineStart = l.current
+}
+
+// Checks whether the rest of the current line is blank or a comment, which don't change the indentation.
+func (l *lexer) blankLine() bool {
+	n := l.current
+	for n < len(l.source) && (l.source[n] == ' ' || l.source[n] == '\t' || l.source[n] == '\r') {
+		n++
 	}
+	return n >= len(l.source) || l.source[n] == '\n' || strings.HasPrefix(l.source[n:], "//")
 }
 
 // Advances current and adds the current token
@@ -244,13 +297,23 @@ func (l *lexer) scanToken() {
 
 	// Creates single-character tokens
 	case '(':
+		l.brackets++
 		l.addToken(LEFT_PAREN, nil)
 	case ')':
+		l.brackets--
 		l.addToken(RIGHT_PAREN, nil)
 	case '{':
+		l.brackets++
 		l.addToken(LEFT_BRACE, nil)
 	case '}':
+		l.brackets--
 		l.addToken(RIGHT_BRACE, nil)
+	case '[':
+		l.brackets++
+		l.addToken(LEFT_BRACKET, nil)
+	case ']':
+		l.brackets--
+		l.addToken(RIGHT_BRACKET, nil)
 	case ',':
 		l.addToken(COMMA, nil)
 	case ';':
@@ -258,7 +321,13 @@ func (l *lexer) scanToken() {
 	case ':':
 		l.addToken(COLON, nil)
 	case '.':
-		l.addToken(DOT, nil)
+		if l.peek() == '.' && l.peekNext() == '.' {
+			l.advance()
+			l.advance()
+			l.addToken(DOT_DOT_DOT, nil)
+		} else {
+			l.addToken(DOT, nil)
+		}
 	case '*':
 		l.addToken(STAR, nil)
 
@@ -279,14 +348,14 @@ func (l *lexer) scanToken() {
 	// Differentiate between SLASH and a comment (which ignores the rest of the line)
 	case '/':
 		if l.peek() == '/' {
+			// The comment's new line is left to end the statement before it, if there is one.
 			for l.peek() != '\n' && !l.isAtEnd() {
 				l.advance()
 			}
 
-			if l.peek() == '\n' && !l.isAtEnd() {
-				l.advance()
+			if l.Comments {
+				l.addToken(COMMENT, nil)
 			}
-
 			break
 		} else {
 			l.addToken(SLASH, nil)
@@ -294,15 +363,21 @@ func (l *lexer) scanToken() {
 
 	// Whitespace and meaningless characters
 	case '\n':
+		// Statements continue over new lines inside brackets, ex: a call with one argument per line.
+		if l.brackets > 0 {
+			l.newLine()
+			break
+		}
 		l.getNewline()
-		l.line++
-		if !l.isAtEnd() && l.peek() != '\n' {
+		l.newLine()
+		if !l.blankLine() {
 			l.getDent()
 		}
 	case '~':
 		// Skip a newline if it's preceded by a '~' to allow a statement to continue to a new line of text.
 		if l.peek() == '\n' {
 			l.advance()
+			l.newLine()
 		}
 	case ' ':
 	case '\r':
@@ -327,7 +402,16 @@ func (l *lexer) scanToken() {
 
 // Loops over all characters in souce, creating tokens as it goes, places EOF token at end of source
 func (l *lexer) ScanTokens() ([]Token, bool) {
-	l.getDent()
+	// Skip a shebang line (ex: "#!/usr/bin/env friston"), but not its new line, so line numbers stay the same.
+	if strings.HasPrefix(l.source, "#!") {
+		for !l.isAtEnd() && l.peek() != '\n' {
+			l.advance()
+		}
+	}
+
+	if !l.blankLine() {
+		l.getDent()
+	}
 
 	for !l.isAtEnd() {
 		l.start = l.current
@@ -335,6 +419,6 @@ func (l *lexer) ScanTokens() ([]Token, bool) {
 	}
 
 	l.getNewline()
-	l.tokens = append(l.tokens, Token{EOF, "EOF", nil, l.line})
+	l.tokens = append(l.tokens, Token{EOF, "EOF", nil, l.line, l.current - l.lineStart + 1})
 	return l.tokens, l.hadError
 }
@@ -1,5 +1,7 @@
 package lexer
 
+import "sort"
+
 // Declare constant names for TokenTypes, use iota to increment values
 type TokenType int
 
@@ -9,6 +11,8 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
 	DOT
 	SEMICOLON
@@ -25,6 +29,7 @@ const (
 	EQUAL_EQUAL
 	BANG
 	BANG_EQUAL
+	DOT_DOT_DOT
 	LESS
 	LESS_EQUAL
 	GREATER
@@ -51,10 +56,17 @@ const (
 	LET
 	RETURN
 	WHILE
+	TRY
+	CATCH
+	FINALLY
+	THROW
+	IMPORT
+	AS
 
 	INDENT
 	DEDENT
 	NEWLINE
+	COMMENT
 	EOF
 )
 
@@ -74,6 +86,22 @@ var keywords = map[string]TokenType{
 	"let" : LET,
 	"return" : RETURN,
 	"while" : WHILE,
+	"try" : TRY,
+	"catch" : CATCH,
+	"finally" : FINALLY,
+	"throw" : THROW,
+	"import" : IMPORT,
+	"as" : AS,
+}
+
+// Returns the reserved keywords, sorted.
+func Keywords() []string {
+	var words []string
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
 }
 
 
@@ -89,84 +117,104 @@ func (t TokenType) typeString() string {
 	case 3:
 		return "RIGHT_BRACE"
 	case 4:
-		return "COMMA"
+		return "LEFT_BRACKET"
 	case 5:
-		return "DOT"
+		return "RIGHT_BRACKET"
 	case 6:
-		return "SEMICOLON"
+		return "COMMA"
 	case 7:
-		return "COLON"
+		return "DOT"
 	case 8:
-		return "STAR"
+		return "SEMICOLON"
 	case 9:
-		return "SLASH"
+		return "COLON"
 	case 10:
-		return "PLUS"
+		return "STAR"
 	case 11:
-		return "PLUS_PLUS"
+		return "SLASH"
 	case 12:
-		return "MINUS"
+		return "PLUS"
 	case 13:
-		return "MINUS_MINUS"
+		return "PLUS_PLUS"
 	case 14:
-		return "EQUAL"
+		return "MINUS"
 	case 15:
-		return "EQUAL_EQUAL"
+		return "MINUS_MINUS"
 	case 16:
-		return "BANG"
+		return "EQUAL"
 	case 17:
-		return "BANG_EQUAL"
+		return "EQUAL_EQUAL"
 	case 18:
-		return "LESS"
+		return "BANG"
 	case 19:
-		return "LESS_EQUAL"
+		return "BANG_EQUAL"
 	case 20:
-		return "GREATER"
+		return "DOT_DOT_DOT"
 	case 21:
-		return "GREATER_EQUAL"
+		return "LESS"
 	case 22:
-		return "NUMBER"
+		return "LESS_EQUAL"
 	case 23:
-		return "STRING"
+		return "GREATER"
 	case 24:
-		return "IDENTIFIER"
+		return "GREATER_EQUAL"
 	case 25:
-		return "AND"
+		return "NUMBER"
 	case 26:
-		return "CLASS"
+		return "STRING"
 	case 27:
-		return "ELSE"
+		return "IDENTIFIER"
 	case 28:
-		return "FALSE"
+		return "AND"
 	case 29:
-		return "FOR"
+		return "CLASS"
 	case 30:
-		return "FUNCTION"
+		return "ELSE"
 	case 31:
-		return "IF"
+		return "FALSE"
 	case 32:
-		return "NIL"
+		return "FOR"
 	case 33:
-		return "OR"
+		return "FUNCTION"
 	case 34:
-		return "THEN"
+		return "IF"
 	case 35:
-		return "THIS"
+		return "NIL"
 	case 36:
-		return "TRUE"
+		return "OR"
 	case 37:
-		return "LET"
+		return "THEN"
 	case 38:
-		return "RETURN"
+		return "THIS"
 	case 39:
-		return "WHILE"
+		return "TRUE"
 	case 40:
-		return "INDENT"
+		return "LET"
 	case 41:
-		return "DEDENT"
+		return "RETURN"
 	case 42:
-		return "NEWLINE"
+		return "WHILE"
 	case 43:
+		return "TRY"
+	case 44:
+		return "CATCH"
+	case 45:
+		return "FINALLY"
+	case 46:
+		return "THROW"
+	case 47:
+		return "IMPORT"
+	case 48:
+		return "AS"
+	case 49:
+		return "INDENT"
+	case 50:
+		return "DEDENT"
+	case 51:
+		return "NEWLINE"
+	case 52:
+		return "COMMENT"
+	case 53:
 		return "EOF"
 	}
 
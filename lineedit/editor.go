@@ -0,0 +1,375 @@
+// Package lineedit reads lines from a terminal with cursor movement, history and tab completion.
+// When the input isn't a terminal (ex: a pipe), lines are read as they are.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupted is returned by ReadLine when the line is cancelled with ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Default number of lines kept in the history.
+const DefaultMaxHistory = 1000
+
+type Editor struct {
+	// Returns the completions of the word before the cursor, or nil.
+	Complete func(word string) []string
+	// Number of lines kept in the history (and its file).
+	MaxHistory  int
+	in          *os.File
+	out         io.Writer
+	reader      *bufio.Reader
+	history     []string
+	historyFile string
+}
+
+// Reads lines from in through reader, which should be the only buffered reader of in, so it can be shared with
+// anything else reading in (ex: a program's readLine) without either taking the other's input.
+func NewEditor(in *os.File, reader *bufio.Reader, out io.Writer) *Editor {
+	return &Editor{
+		MaxHistory: DefaultMaxHistory,
+		in:         in,
+		out:        out,
+		reader:     reader,
+	}
+}
+
+// Loads the history from a file, which each new line is then added to. A missing file is created later.
+func (e *Editor) UseHistoryFile(path string) error {
+	e.historyFile = path
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	e.trimHistory()
+	return nil
+}
+
+// Adds a line to the history, unless it's blank or repeats the last line.
+func (e *Editor) AddHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+
+	// The file is rewritten when it grows past twice the limit, so it isn't rewritten for every line.
+	if e.trimHistory() {
+		e.saveHistory()
+	} else if e.historyFile != "" {
+		file, err := os.OpenFile(e.historyFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err == nil {
+			fmt.Fprintln(file, line)
+			file.Close()
+		}
+	}
+}
+
+func (e *Editor) History() []string {
+	return e.history
+}
+
+// Drops the oldest lines past MaxHistory, and returns whether the file has grown enough to be rewritten.
+func (e *Editor) trimHistory() bool {
+	if len(e.history) <= e.MaxHistory {
+		return false
+	}
+	e.history = e.history[len(e.history)-e.MaxHistory:]
+	return true
+}
+
+func (e *Editor) saveHistory() {
+	if e.historyFile != "" {
+		ioutil.WriteFile(e.historyFile, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+	}
+}
+
+// Reads a line after showing prompt, without its line ending. At the end of the input (or ctrl-D on an empty line)
+// it returns io.EOF, and ErrInterrupted when ctrl-C cancels the line. Lines are added to the history.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	restore, err := makeRaw(e.in.Fd())
+	if err != nil {
+		return e.readPlain(prompt)
+	}
+	defer restore()
+
+	line, err := e.edit(prompt)
+	if err == nil {
+		e.AddHistory(line)
+	}
+	return line, err
+}
+
+// Reads a line from input that isn't a terminal.
+func (e *Editor) readPlain(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+
+	line, err := e.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), err
+}
+
+// Control keys, ex: ctrlA is ctrl-A.
+const (
+	ctrlA     = 1
+	ctrlB     = 2
+	ctrlC     = 3
+	ctrlD     = 4
+	ctrlE     = 5
+	ctrlF     = 6
+	ctrlH     = 8
+	tab       = 9
+	ctrlK     = 11
+	ctrlL     = 12
+	enter     = 13
+	ctrlN     = 14
+	ctrlP     = 16
+	ctrlU     = 21
+	ctrlW     = 23
+	escape    = 27
+	backspace = 127
+)
+
+// The line being edited in raw mode.
+type state struct {
+	*Editor
+	prompt  string
+	line    []rune
+	pos     int
+	history int
+	// The line being typed before moving back through the history.
+	pending []rune
+}
+
+func (e *Editor) edit(prompt string) (string, error) {
+	s := state{Editor: e, prompt: prompt, history: len(e.history)}
+	s.refresh()
+
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			fmt.Fprint(e.out, "\r\n")
+			if len(s.line) > 0 {
+				return string(s.line), nil
+			}
+			return "", err
+		}
+
+		switch r {
+		case enter, '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(s.line), nil
+		case ctrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case ctrlD:
+			if len(s.line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			s.delete()
+		case ctrlA:
+			s.pos = 0
+		case ctrlE:
+			s.pos = len(s.line)
+		case ctrlB:
+			s.left()
+		case ctrlF:
+			s.right()
+		case backspace, ctrlH:
+			if s.pos > 0 {
+				s.pos--
+				s.delete()
+			}
+		case ctrlK:
+			s.line = s.line[:s.pos]
+		case ctrlU:
+			s.line = s.line[s.pos:]
+			s.pos = 0
+		case ctrlW:
+			s.deleteWord()
+		case ctrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case ctrlP:
+			s.moveHistory(-1)
+		case ctrlN:
+			s.moveHistory(1)
+		case tab:
+			s.complete()
+		case escape:
+			s.escape()
+		default:
+			if unicode.IsPrint(r) {
+				s.insert([]rune{r})
+			}
+		}
+
+		s.refresh()
+	}
+}
+
+// Handles an escape sequence, ex: "\x1b[A" for the up arrow, or "\x1b[3~" for delete.
+func (s *state) escape() {
+	kind, _, _ := s.reader.ReadRune()
+	if kind != '[' && kind != 'O' {
+		return
+	}
+
+	// Read the numeric parameters up to the final character.
+	param := ""
+	final, _, _ := s.reader.ReadRune()
+	for final >= '0' && final <= '9' || final == ';' {
+		param += string(final)
+		final, _, _ = s.reader.ReadRune()
+	}
+
+	switch {
+	case final == 'A':
+		s.moveHistory(-1)
+	case final == 'B':
+		s.moveHistory(1)
+	case final == 'C':
+		s.right()
+	case final == 'D':
+		s.left()
+	case final == 'H' || final == '~' && (param == "1" || param == "7"):
+		s.pos = 0
+	case final == 'F' || final == '~' && (param == "4" || param == "8"):
+		s.pos = len(s.line)
+	case final == '~' && param == "3":
+		s.delete()
+	}
+}
+
+// Redraws the line, and moves the cursor back from the end of the line to its position.
+func (s *state) refresh() {
+	fmt.Fprintf(s.out, "\r%s%s\x1b[K", s.prompt, string(s.line))
+	if back := len(s.line) - s.pos; back > 0 {
+		fmt.Fprintf(s.out, "\x1b[%dD", back)
+	}
+}
+
+func (s *state) insert(runes []rune) {
+	line := append([]rune{}, s.line[:s.pos]...)
+	line = append(line, runes...)
+	s.line = append(line, s.line[s.pos:]...)
+	s.pos += len(runes)
+}
+
+// Deletes the character under the cursor.
+func (s *state) delete() {
+	if s.pos < len(s.line) {
+		s.line = append(s.line[:s.pos], s.line[s.pos+1:]...)
+	}
+}
+
+// Deletes the word before the cursor, and the spaces after it.
+func (s *state) deleteWord() {
+	start := s.pos
+	for start > 0 && s.line[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && s.line[start-1] != ' ' {
+		start--
+	}
+	s.line = append(s.line[:start], s.line[s.pos:]...)
+	s.pos = start
+}
+
+func (s *state) left() {
+	if s.pos > 0 {
+		s.pos--
+	}
+}
+
+func (s *state) right() {
+	if s.pos < len(s.line) {
+		s.pos++
+	}
+}
+
+// Replaces the line with an older (-1) or newer (1) line from the history.
+func (s *state) moveHistory(direction int) {
+	next := s.history + direction
+	if next < 0 || next > len(s.Editor.history) {
+		return
+	}
+
+	if s.history == len(s.Editor.history) {
+		s.pending = s.line
+	}
+	s.history = next
+
+	if next == len(s.Editor.history) {
+		s.line = s.pending
+	} else {
+		s.line = []rune(s.Editor.history[next])
+	}
+	s.pos = len(s.line)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Completes the word before the cursor as far as all its completions agree, or lists them if it can't go further.
+// At the start of a line, tab indents instead.
+func (s *state) complete() {
+	start := s.pos
+	for start > 0 && isWordRune(s.line[start-1]) {
+		start--
+	}
+	word := string(s.line[start:s.pos])
+
+	if strings.TrimSpace(string(s.line[:s.pos])) == "" {
+		s.insert([]rune("    "))
+		return
+	}
+	if word == "" || s.Complete == nil {
+		return
+	}
+
+	completions := s.Complete(word)
+	if len(completions) == 0 {
+		fmt.Fprint(s.out, "\a")
+		return
+	}
+
+	prefix := commonPrefix(completions)
+	if len(prefix) > len(word) {
+		s.insert([]rune(prefix[len(word):]))
+	} else if len(completions) > 1 {
+		sort.Strings(completions)
+		fmt.Fprintf(s.out, "\r\n%s\r\n", strings.Join(completions, "  "))
+	}
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
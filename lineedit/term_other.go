@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package lineedit
+
+import "errors"
+
+// Raw mode isn't supported here, so lines are read as they are.
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw mode isn't supported on this platform")
+}
@@ -0,0 +1,605 @@
+package lint
+
+import (
+	"fmt"
+	"friston/ast"
+	"friston/errors"
+	"friston/interpreter"
+	"friston/lexer"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// A declared name, and how it's used.
+type variable struct {
+	name     lexer.Token
+	kind     string
+	used     bool
+	assigned bool
+	// The declaration of a function, for checking calls to it.
+	function *ast.FuncDecl
+	typ      staticType
+	// The variable as it's given to Resolve's callers.
+	declaration *Declaration
+}
+
+type scope struct {
+	parent *scope
+	vars   map[string]*variable
+	// Variables in the order they're declared, so they're reported in order.
+	order  []*variable
+	symbol *Scope
+}
+
+func (s *scope) lookup(name string) *variable {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// A variable read or assigned, and its declaration once it's found.
+type reference struct {
+	name   lexer.Token
+	scope  *scope
+	assign bool
+	v      *variable
+}
+
+// The type of an expression ("number", "string", "bool", "nil", "list" or "function"), or "" if it isn't known.
+// Types are only known once the whole program is read, as a variable can be assigned after it's used.
+type staticType func() string
+
+func known(t string) staticType {
+	return func() string { return t }
+}
+
+// A visitor that walks a program once, declaring names in scopes like the interpreter does. Expression visits
+// return their staticType. Checks that need the whole program (ex: whether a variable is ever read) run at the end.
+type checker struct {
+	path  string
+	rules map[string]bool
+	// Names the host defines.
+	globals map[string]bool
+	scope   *scope
+	// How many function bodies the checker is in.
+	functions int
+	// References in functions to names that weren't declared yet.
+	unresolved []*reference
+	later      []func()
+	// Evaluates constant conditions.
+	constants   interpreter.Interpreter
+	diagnostics []Diagnostic
+	symbols     Symbols
+}
+
+func newChecker(path string, rules map[string]bool, globals []string) *checker {
+	c := checker{path: path, rules: rules, globals: map[string]bool{}}
+	c.beginScope(1, nil)
+	for _, name := range globals {
+		c.globals[name] = true
+	}
+	c.constants = interpreter.NewInterpreter(false, ioutil.Discard, ioutil.Discard, nil, interpreter.Pure)
+	return &c
+}
+
+func (c *checker) program(stmts []ast.Statement) {
+	c.statements(stmts)
+
+	// Functions can use globals declared after them, as they're only looked up when the function is called.
+	for _, ref := range c.unresolved {
+		ref.v = ref.scope.lookup(ref.name.Lexeme)
+		c.resolved(ref)
+	}
+	for _, check := range c.later {
+		check()
+	}
+}
+
+func (c *checker) report(rule string, at lexer.Token, format string, args ...interface{}) {
+	if c.rules[rule] {
+		c.diagnostics = append(c.diagnostics, Diagnostic{c.path, at.Line, at.Column, rule, fmt.Sprintf(format, args...)})
+	}
+}
+
+// Starts a scope from a line, which lasts until the last statement in it. Body is the block its statements are in.
+func (c *checker) beginScope(line int, body ast.Statement) {
+	symbol := &Scope{Start: line, End: line, Body: ast.Line(body)}
+	if c.scope != nil {
+		symbol.Parent = c.scope.symbol
+	}
+	c.scope = &scope{parent: c.scope, vars: map[string]*variable{}, symbol: symbol}
+	c.symbols.Scopes = append(c.symbols.Scopes, symbol)
+}
+
+// Ends a scope, reporting its variables that are never read. Globals aren't reported, as modules declare them for importers.
+func (c *checker) endScope() {
+	s := c.scope
+	c.scope = s.parent
+	if s.symbol.End > c.scope.symbol.End {
+		c.scope.symbol.End = s.symbol.End
+	}
+
+	c.later = append(c.later, func() {
+		for _, v := range s.order {
+			if v.used || strings.HasPrefix(v.name.Lexeme, "_") {
+				continue
+			}
+			if v.kind == "parameter" {
+				c.report("unused-parameter", v.name, "Parameter '%s' is never used.", v.name.Lexeme)
+			} else {
+				c.report("unused-variable", v.name, "'%s' is never used.", v.name.Lexeme)
+			}
+		}
+	})
+}
+
+func (c *checker) declare(name lexer.Token, kind string, typ staticType) *variable {
+	if previous, ok := c.scope.vars[name.Lexeme]; ok {
+		c.report("shadow", name, "'%s' is already declared on line %d.", name.Lexeme, previous.name.Line)
+	} else if outer := c.scope.parent.lookup(name.Lexeme); outer != nil {
+		c.report("shadow", name, "'%s' shadows the declaration on line %d.", name.Lexeme, outer.name.Line)
+	} else if _, ok := interpreter.Constants[name.Lexeme]; ok {
+		c.report("shadow", name, "'%s' shadows the constant %s.", name.Lexeme, name.Lexeme)
+	}
+
+	v := &variable{name: name, kind: kind, typ: typ}
+	v.declaration = &Declaration{Name: name, Kind: kind, Scope: c.scope.symbol}
+	c.symbols.Declarations = append(c.symbols.Declarations, v.declaration)
+	c.scope.vars[name.Lexeme] = v
+	c.scope.order = append(c.scope.order, v)
+	return v
+}
+
+// Looks up a name where it's used. Names in functions that aren't declared yet are looked up again at the end.
+func (c *checker) reference(name lexer.Token, assign bool) *reference {
+	ref := &reference{name: name, scope: c.scope, assign: assign}
+	ref.v = c.scope.lookup(name.Lexeme)
+	if ref.v == nil && c.functions > 0 {
+		c.unresolved = append(c.unresolved, ref)
+	} else {
+		c.resolved(ref)
+	}
+	return ref
+}
+
+// Marks a reference's variable as read or assigned, or reports it if it isn't declared anywhere.
+func (c *checker) resolved(ref *reference) {
+	name := ref.name.Lexeme
+	if ref.v != nil {
+		c.symbols.References = append(c.symbols.References, Reference{ref.name, ref.v.declaration})
+		if ref.assign {
+			ref.v.assigned = true
+		} else {
+			ref.v.used = true
+		}
+		return
+	}
+
+	c.symbols.References = append(c.symbols.References, Reference{ref.name, nil})
+	if _, ok := interpreter.Natives[name]; ok {
+		return
+	}
+	if _, ok := interpreter.Constants[name]; ok || c.globals[name] {
+		return
+	}
+
+	if ref.assign {
+		c.report("undeclared", ref.name, "Assignment to undeclared variable '%s'.", name)
+	} else {
+		c.report("undeclared", ref.name, "Undeclared variable '%s'.", name)
+	}
+}
+
+func (c *checker) expr(expr ast.Expression) staticType {
+	return expr.Accept(c).(staticType)
+}
+
+// Checks the statements of a block, reporting the first one after a statement that always returns or throws.
+func (c *checker) statements(stmts []ast.Statement) {
+	exited, reported := "", false
+	for _, stmt := range stmts {
+		if line := ast.Line(stmt); line > c.scope.symbol.End {
+			c.scope.symbol.End = line
+		}
+		if exited != "" && !reported {
+			c.report("unreachable", ast.Start(stmt), "Unreachable code after %s.", exited)
+			reported = true
+		}
+		stmt.Accept(c)
+
+		if exited == "" {
+			exited = exits(stmt)
+		}
+	}
+}
+
+// Returns "return" or "throw" if a statement always ends its function that way, or "" if it doesn't.
+func exits(stmt ast.Statement) string {
+	switch s := stmt.(type) {
+	case ast.ReturnStmt:
+		return "return"
+	case ast.ThrowStmt:
+		return "throw"
+	case ast.Block:
+		for _, stmt := range s.Stmts {
+			if exit := exits(stmt); exit != "" {
+				return exit
+			}
+		}
+	case ast.IfStmt:
+		if s.ElseBranch != nil {
+			if exit := exits(s.ThenBranch); exit != "" && exits(s.ElseBranch) != "" {
+				return exit
+			}
+		}
+	}
+	return ""
+}
+
+// Checks the condition of an if or loop, which is constant if it only has literals. 'while true' loops on purpose.
+func (c *checker) condition(cond ast.Expression, loop bool) {
+	c.expr(cond)
+	if !constant(cond) {
+		return
+	}
+	if literal, ok := cond.(ast.Literal); ok && loop && literal.X.TType == lexer.TRUE {
+		return
+	}
+
+	if truth, ok := c.truth(cond); ok {
+		c.report("constant-condition", ast.Start(cond), "Condition is always %v.", truth)
+	}
+}
+
+func constant(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case ast.Literal:
+		return true
+	case ast.Group:
+		return constant(e.X)
+	case ast.Unary:
+		return constant(e.X)
+	case ast.Binary:
+		return constant(e.X) && constant(e.Y)
+	case ast.Logic:
+		return constant(e.X) && constant(e.Y)
+	}
+	return false
+}
+
+// Evaluates a constant expression, returning false for ok if it raises an error (ex: 1 / 0).
+func (c *checker) truth(expr ast.Expression) (truth bool, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isErr := r.(errors.RuntimeError); !isErr {
+				panic(r)
+			}
+			ok = false
+		}
+	}()
+
+	switch value := expr.Accept(c.constants).(type) {
+	case nil:
+		return false, true
+	case bool:
+		return value, true
+	case float64:
+		return value != 0, true
+	case string:
+		return value != "", true
+	}
+	return true, true
+}
+
+// Checks a call to a function declared once and never assigned, or to a native.
+func (c *checker) checkCall(ref *reference, call ast.Call) {
+	if ref.v != nil {
+		if ref.v.function != nil && !ref.v.assigned {
+			c.checkUserCall(*ref.v.function, call)
+		}
+	} else if native, ok := interpreter.Natives[ref.name.Lexeme]; ok {
+		c.checkNativeCall(ref.name.Lexeme, native.Function, call)
+	}
+}
+
+// Matches keyword arguments to parameters like the interpreter, then checks the number of arguments.
+func (c *checker) checkUserCall(decl ast.FuncDecl, call ast.Call) {
+	var params []string
+	for _, param := range decl.Parameters {
+		params = append(params, param.Lexeme)
+	}
+	named := len(params)
+	if decl.Rest {
+		named--
+	}
+
+	count := len(call.Arguments)
+	filled := map[int]bool{}
+	for _, keyword := range call.Keywords {
+		n := 0
+		for n < named && params[n] != keyword.Name.Lexeme {
+			n++
+		}
+
+		if n == named {
+			c.report("arity", keyword.Name, "'%s' has no keyword argument '%s'.", decl.Name.Lexeme, keyword.Name.Lexeme)
+		} else if n < len(call.Arguments) {
+			c.report("arity", keyword.Name, "Argument '%s' is given more than once.", keyword.Name.Lexeme)
+		} else {
+			filled[n] = true
+			if n >= count {
+				count = n + 1
+			}
+		}
+	}
+
+	for n := len(call.Arguments); n < count; n++ {
+		if !filled[n] && (n >= len(decl.Defaults) || decl.Defaults[n] == nil) {
+			c.report("arity", ast.Start(call), "Missing argument '%s' in call to '%s'.", params[n], decl.Name.Lexeme)
+			return
+		}
+	}
+
+	arity := interpreter.UserFunc{Parameters: params, Defaults: decl.Defaults, Rest: decl.Rest}.Arity()
+	if !arity.Accepts(count) {
+		c.report("arity", ast.Start(call), "'%s' expects %v, but got %d.", decl.Name.Lexeme, arity, count)
+	}
+}
+
+// Natives take keyword arguments as options, which don't count towards their arity.
+func (c *checker) checkNativeCall(name string, native interpreter.Function, call ast.Call) {
+	var options []string
+	if f, ok := native.(interpreter.OptionsFunction); ok {
+		options = f.Options()
+	}
+
+	for _, keyword := range call.Keywords {
+		found := false
+		for _, option := range options {
+			found = found || option == keyword.Name.Lexeme
+		}
+		if !found {
+			c.report("arity", keyword.Name, "'%s' has no keyword argument '%s'.", name, keyword.Name.Lexeme)
+		}
+	}
+
+	if arity := native.Arity(); !arity.Accepts(len(call.Arguments)) {
+		c.report("arity", ast.Start(call), "'%s' expects %v, but got %d.", name, arity, len(call.Arguments))
+	}
+}
+
+// Expressions:
+
+func (c *checker) VisitBinary(b ast.Binary) interface{} {
+	x, y := c.expr(b.X), c.expr(b.Y)
+
+	switch b.Op.TType {
+	case lexer.EQUAL_EQUAL, lexer.BANG_EQUAL:
+		op := b.Op
+		c.later = append(c.later, func() {
+			if tx, ty := x(), y(); tx != "" && ty != "" && tx != ty {
+				c.report("type-mismatch", op, "'%s' between %s and %s is always %v.", op.Lexeme, tx, ty, op.TType == lexer.BANG_EQUAL)
+			}
+		})
+		return known("bool")
+	case lexer.LESS, lexer.LESS_EQUAL, lexer.GREATER, lexer.GREATER_EQUAL:
+		return known("bool")
+	case lexer.MINUS, lexer.STAR, lexer.SLASH:
+		return known("number")
+	case lexer.PLUS:
+		// Strings can be added to anything, but numbers only to numbers.
+		return staticType(func() string {
+			if tx := x(); tx == "string" || tx == "number" && y() == "number" {
+				return tx
+			}
+			return ""
+		})
+	}
+	return known("")
+}
+
+func (c *checker) VisitLogic(l ast.Logic) interface{} {
+	x, y := c.expr(l.X), c.expr(l.Y)
+	return staticType(func() string {
+		if tx := x(); tx == y() {
+			return tx
+		}
+		return ""
+	})
+}
+
+func (c *checker) VisitUnary(u ast.Unary) interface{} {
+	c.expr(u.X)
+	if u.Op.TType == lexer.BANG {
+		return known("bool")
+	}
+	return known("number")
+}
+
+func (c *checker) VisitGroup(g ast.Group) interface{} {
+	return c.expr(g.X)
+}
+
+func (c *checker) VisitLiteral(l ast.Literal) interface{} {
+	switch l.X.TType {
+	case lexer.NUMBER:
+		return known("number")
+	case lexer.STRING:
+		return known("string")
+	case lexer.TRUE, lexer.FALSE:
+		return known("bool")
+	case lexer.NIL:
+		return known("nil")
+	}
+	return known("")
+}
+
+func (c *checker) VisitVariable(vr ast.Variable) interface{} {
+	ref := c.reference(vr.Name, false)
+	return staticType(func() string {
+		if ref.v == nil || ref.v.assigned || ref.v.typ == nil {
+			return ""
+		}
+		return ref.v.typ()
+	})
+}
+
+func (c *checker) VisitAssignment(a ast.Assignment) interface{} {
+	value := c.expr(a.Value)
+	c.reference(a.Name, true)
+	return value
+}
+
+func (c *checker) VisitCall(call ast.Call) interface{} {
+	if vr, ok := call.Callee.(ast.Variable); ok {
+		ref := c.reference(vr.Name, false)
+		c.later = append(c.later, func() { c.checkCall(ref, call) })
+	} else {
+		c.expr(call.Callee)
+	}
+
+	for _, arg := range call.Arguments {
+		c.expr(arg)
+	}
+	for _, keyword := range call.Keywords {
+		c.expr(keyword.Value)
+	}
+	return known("")
+}
+
+func (c *checker) VisitGet(g ast.Get) interface{} {
+	c.expr(g.Object)
+	return known("")
+}
+
+func (c *checker) VisitIndex(ix ast.Index) interface{} {
+	c.expr(ix.Object)
+	c.expr(ix.Index)
+	return known("")
+}
+
+func (c *checker) VisitListLiteral(l ast.ListLiteral) interface{} {
+	for _, element := range l.Elements {
+		c.expr(element)
+	}
+	return known("list")
+}
+
+// Statements, which declare names in the same scopes as the interpreter. Only blocks start a scope, so a branch
+// that isn't a block declares in the enclosing one.
+
+func (c *checker) VisitExprStmt(e ast.ExprStmt) interface{} {
+	c.expr(e.Expr)
+	return nil
+}
+
+func (c *checker) VisitIfStmt(stmt ast.IfStmt) interface{} {
+	c.condition(stmt.Condition, false)
+	stmt.ThenBranch.Accept(c)
+	if stmt.ElseBranch != nil {
+		stmt.ElseBranch.Accept(c)
+	}
+	return nil
+}
+
+func (c *checker) VisitWhileStmt(stmt ast.WhileStmt) interface{} {
+	c.condition(stmt.Condition, true)
+	stmt.LoopBranch.Accept(c)
+	return nil
+}
+
+func (c *checker) VisitForStmt(stmt ast.ForStmt) interface{} {
+	c.beginScope(stmt.Keyword.Line, stmt.LoopBranch)
+	if stmt.Initializer != nil {
+		stmt.Initializer.Accept(c)
+	}
+	c.condition(stmt.Condition, true)
+	stmt.LoopBranch.Accept(c)
+	c.expr(stmt.Increment)
+	c.endScope()
+	return nil
+}
+
+// A function's parameters and body share a scope. Defaults can use the parameters before them.
+func (c *checker) VisitFuncDecl(f ast.FuncDecl) interface{} {
+	v := c.declare(f.Name, "function", known("function"))
+	v.function = &f
+	v.declaration.Function = &f
+
+	c.beginScope(f.Name.Line, f.Block)
+	v.declaration.Body = c.scope.symbol
+	c.functions++
+	for n, param := range f.Parameters {
+		if n < len(f.Defaults) && f.Defaults[n] != nil {
+			c.expr(f.Defaults[n])
+		}
+		c.declare(param, "parameter", nil)
+	}
+	c.statements(f.Block.Stmts)
+	c.functions--
+	c.endScope()
+	return nil
+}
+
+func (c *checker) VisitVarDecl(d ast.VarDecl) interface{} {
+	typ := known("nil")
+	if d.Initializer != nil {
+		typ = c.expr(d.Initializer)
+	}
+	c.declare(d.Name, "variable", typ)
+	return nil
+}
+
+func (c *checker) VisitReturn(r ast.ReturnStmt) interface{} {
+	if r.Value != nil {
+		c.expr(r.Value)
+	}
+	return nil
+}
+
+func (c *checker) VisitTryStmt(stmt ast.TryStmt) interface{} {
+	stmt.TryBranch.Accept(c)
+
+	// The caught error is declared in a scope around the catch branch.
+	if stmt.CatchBranch != nil {
+		c.beginScope(ast.Line(stmt.CatchBranch), stmt.CatchBranch)
+		if stmt.CatchName.Lexeme != "" {
+			c.declare(stmt.CatchName, "catch", nil)
+		}
+		stmt.CatchBranch.Accept(c)
+		c.endScope()
+	}
+
+	if stmt.FinallyBranch != nil {
+		stmt.FinallyBranch.Accept(c)
+	}
+	return nil
+}
+
+func (c *checker) VisitThrowStmt(stmt ast.ThrowStmt) interface{} {
+	c.expr(stmt.Value)
+	return nil
+}
+
+// Modules are named after their file unless they're imported with 'as'.
+func (c *checker) VisitImportStmt(stmt ast.ImportStmt) interface{} {
+	name := stmt.Name
+	if name.Lexeme == "" {
+		name = stmt.Path
+		path, _ := stmt.Path.Literal.(string)
+		name.Lexeme = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	c.declare(name, "import", known(""))
+	return nil
+}
+
+func (c *checker) VisitBlock(b ast.Block) interface{} {
+	c.beginScope(ast.Line(b), b)
+	c.statements(b.Stmts)
+	c.endScope()
+	return nil
+}
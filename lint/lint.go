@@ -0,0 +1,100 @@
+// Package lint checks friston programs for likely mistakes that still parse, ex: unused variables.
+package lint
+
+import (
+	"fmt"
+	"friston/ast"
+	"sort"
+)
+
+type Rule struct {
+	Name        string
+	Description string
+}
+
+// Every rule, in the order they're listed by 'friston lint -rules'.
+var Rules = []Rule{
+	{"unused-variable", "a local variable, function or caught error is never read"},
+	{"unused-parameter", "a parameter is never read (parameters starting with '_' are ignored)"},
+	{"shadow", "a declaration hides one in an enclosing scope or a constant (ex: pi), or redeclares one in the same scope"},
+	{"unreachable", "a statement comes after a return or throw"},
+	{"undeclared", "a variable is read or assigned without being declared"},
+	{"arity", "a call to a known function has the wrong number of arguments, or an unknown keyword"},
+	{"constant-condition", "an if or while condition is always true or always false ('while true' is allowed)"},
+	{"type-mismatch", "'==' or '!=' compares values that always have different types"},
+}
+
+// Chooses the rules to run: all of them, or only those in Enable, minus those in Disable.
+// Globals are names defined by the host before the program runs, ex: the friston command's 'args'.
+type Config struct {
+	Enable  []string
+	Disable []string
+	Globals []string
+}
+
+// A problem found in a program, at a 1-based line and column.
+type Diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Formats a diagnostic the way compilers do, ex: "main.fn:3:5: 'x' is never used. (unused-variable)"
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", d.File, d.Line, d.Column, d.Message, d.Rule)
+}
+
+// Checks a parsed program, returning its diagnostics in source order. Each diagnostic's file is path.
+func Lint(path string, stmts []ast.Statement, config Config) ([]Diagnostic, error) {
+	rules, err := config.rules()
+	if err != nil {
+		return nil, err
+	}
+
+	c := newChecker(path, rules, config.Globals)
+	c.program(stmts)
+
+	sort.SliceStable(c.diagnostics, func(a, b int) bool {
+		da, db := c.diagnostics[a], c.diagnostics[b]
+		if da.Line != db.Line {
+			return da.Line < db.Line
+		}
+		return da.Column < db.Column
+	})
+	return c.diagnostics, nil
+}
+
+func isRule(name string) bool {
+	for _, rule := range Rules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the set of rules to run.
+func (config Config) rules() (map[string]bool, error) {
+	rules := map[string]bool{}
+	for _, name := range config.Enable {
+		if !isRule(name) {
+			return nil, fmt.Errorf("Unknown rule '%s'.", name)
+		}
+		rules[name] = true
+	}
+	if len(config.Enable) == 0 {
+		for _, rule := range Rules {
+			rules[rule.Name] = true
+		}
+	}
+
+	for _, name := range config.Disable {
+		if !isRule(name) {
+			return nil, fmt.Errorf("Unknown rule '%s'.", name)
+		}
+		delete(rules, name)
+	}
+	return rules, nil
+}
@@ -0,0 +1,43 @@
+package lint
+
+import (
+	"friston/lexer"
+	"friston/parser"
+	"testing"
+)
+
+func TestShadowedConstants(t *testing.T) {
+	src := "try then\n" +
+		"    throw \"failed\"\n" +
+		"catch e then\n" +
+		"    println(e)\n" +
+		"function area: pi =\n" +
+		"    return pi\n" +
+		"println(area(1))\n"
+
+	lex := lexer.NewLexer(src, false)
+	tokens, lexErr := lex.ScanTokens()
+	par := parser.NewParser(tokens)
+	stmts, parErr := par.Parse()
+	if lexErr || parErr {
+		t.Fatalf("can't parse %q", src)
+	}
+
+	diagnostics, err := Lint("test.fn", stmts, Config{Enable: []string{"shadow"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Diagnostic{
+		{"test.fn", 3, 7, "shadow", "'e' shadows the constant e."},
+		{"test.fn", 5, 16, "shadow", "'pi' shadows the constant pi."},
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("diagnostics = %v, want %v", diagnostics, want)
+	}
+	for n := range want {
+		if diagnostics[n] != want[n] {
+			t.Errorf("diagnostic %d = %v, want %v", n, diagnostics[n], want[n])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package lint
+
+import (
+	"friston/ast"
+	"friston/lexer"
+	"sort"
+)
+
+// The names declared and used in a program, found with the same scopes as the linter (ex: for go-to-definition).
+type Symbols struct {
+	Declarations []*Declaration
+	// References in source order.
+	References []Reference
+	Scopes     []*Scope
+}
+
+// A declared name. Kind is "variable", "parameter", "function", "import" or "catch".
+// Functions have their declaration, and the scope of their parameters and body.
+type Declaration struct {
+	Name     lexer.Token
+	Kind     string
+	Scope    *Scope
+	Function *ast.FuncDecl
+	Body     *Scope
+}
+
+// A name read or assigned, and its declaration, which is nil for natives and undeclared names.
+type Reference struct {
+	Name        lexer.Token
+	Declaration *Declaration
+}
+
+// The lines a scope covers, from its first statement (or header) to the line its last statement starts on,
+// and the line its first statement is on (0 if it has none).
+type Scope struct {
+	Parent *Scope
+	Start  int
+	End    int
+	Body   int
+}
+
+// Finds the declaration of every name used in a parsed program.
+func Resolve(stmts []ast.Statement) Symbols {
+	c := newChecker("", map[string]bool{}, nil)
+	c.program(stmts)
+
+	refs := c.symbols.References
+	sort.SliceStable(refs, func(a, b int) bool {
+		if refs[a].Name.Line != refs[b].Name.Line {
+			return refs[a].Name.Line < refs[b].Name.Line
+		}
+		return refs[a].Name.Column < refs[b].Name.Column
+	})
+	return c.symbols
+}
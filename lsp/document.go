@@ -0,0 +1,320 @@
+package lsp
+
+import (
+	"friston/ast"
+	"friston/errors"
+	"friston/interpreter"
+	"friston/lexer"
+	"friston/lint"
+	"friston/parser"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// An open document, and what's known about its code. While the text has syntax errors,
+// the symbols are kept from the last version that parsed, so completion still works while typing.
+type document struct {
+	lines   []string
+	errors  []errors.SyntaxError
+	symbols lint.Symbols
+}
+
+func newDocument(text string, previous *document) *document {
+	doc := document{lines: strings.Split(text, "\n")}
+
+	lex := lexer.NewLexer(text, false)
+	lex.Errors = ioutil.Discard
+	tokens, lexErr := lex.ScanTokens()
+
+	var stmts []ast.Statement
+	parErr := true
+	if lexErr {
+		doc.errors = lex.SyntaxErrors
+	} else {
+		par := parser.NewParser(tokens)
+		par.Errors = ioutil.Discard
+		stmts, parErr = par.Parse()
+		doc.errors = par.SyntaxErrors
+	}
+
+	if !parErr {
+		doc.symbols = lint.Resolve(stmts)
+	} else if previous != nil {
+		doc.symbols = previous.symbols
+	}
+	return &doc
+}
+
+// Converts a 1-based line and byte column to a position. Positions past the end of the text are moved back to it.
+func (d *document) position(line int, column int) Position {
+	if line < 1 {
+		return Position{0, 0}
+	} else if line > len(d.lines) {
+		line = len(d.lines)
+		column = len(d.lines[line-1]) + 1
+	}
+
+	text := d.lines[line-1]
+	if column < 1 {
+		column = 1
+	} else if column > len(text)+1 {
+		column = len(text) + 1
+	}
+	return Position{line - 1, len(utf16.Encode([]rune(text[:column-1])))}
+}
+
+// Converts a position back to a 1-based line and byte column.
+func (d *document) column(pos Position) (int, int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos.Line + 1, 1
+	}
+
+	text := d.lines[pos.Line]
+	units, n := 0, 0
+	for n < len(text) && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(text[n:])
+		units += len(utf16.Encode([]rune{r}))
+		n += size
+	}
+	return pos.Line + 1, n + 1
+}
+
+// Returns a 1-based line of the text, or "" if it's past the end (ex: a declaration from an older version).
+func (d *document) line(n int) string {
+	if n < 1 || n > len(d.lines) {
+		return ""
+	}
+	return d.lines[n-1]
+}
+
+func (d *document) tokenRange(tok lexer.Token) Range {
+	return Range{d.position(tok.Line, tok.Column), d.position(tok.Line, tok.Column+len(tok.Lexeme))}
+}
+
+// Syntax errors are underlined from where they are to the end of the line.
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, err := range d.errors {
+		start := d.position(err.Line, err.Column)
+		end := d.position(err.Line, len(d.lines[start.Line])+1)
+		if end == start && start.Character > 0 {
+			start.Character--
+		}
+		diagnostics = append(diagnostics, Diagnostic{Range{start, end}, severityError, "friston", err.Message})
+	}
+	return diagnostics
+}
+
+// Whether a position is on a token, including just after its last character.
+func (d *document) on(tok lexer.Token, pos Position) bool {
+	line, column := d.column(pos)
+	return tok.Line == line && column >= tok.Column && column <= tok.Column+len(tok.Lexeme)
+}
+
+// Returns the name at a position, and its declaration (nil for natives and undeclared names).
+func (d *document) lookup(pos Position) (lexer.Token, *lint.Declaration, bool) {
+	for _, decl := range d.symbols.Declarations {
+		if d.on(decl.Name, pos) {
+			return decl.Name, decl, true
+		}
+	}
+	for _, ref := range d.symbols.References {
+		if d.on(ref.Name, pos) {
+			return ref.Name, ref.Declaration, true
+		}
+	}
+	return lexer.Token{}, nil, false
+}
+
+func (d *document) definition(uri string, pos Position) interface{} {
+	if _, decl, ok := d.lookup(pos); ok && decl != nil {
+		return Location{uri, d.tokenRange(decl.Name)}
+	}
+	return nil
+}
+
+func (d *document) references(uri string, pos Position, includeDeclaration bool) []Location {
+	locations := []Location{}
+	_, decl, ok := d.lookup(pos)
+	if !ok || decl == nil {
+		return locations
+	}
+
+	if includeDeclaration {
+		locations = append(locations, Location{uri, d.tokenRange(decl.Name)})
+	}
+	for _, ref := range d.symbols.References {
+		if ref.Declaration == decl {
+			locations = append(locations, Location{uri, d.tokenRange(ref.Name)})
+		}
+	}
+	return locations
+}
+
+// Natives show their docs, and declared names show the line they're declared on.
+func (d *document) hover(pos Position) interface{} {
+	name, decl, ok := d.lookup(pos)
+	if !ok {
+		return nil
+	}
+	tokRange := d.tokenRange(name)
+
+	var value string
+	if decl != nil {
+		value = "```friston\n" + strings.TrimSpace(d.line(decl.Name.Line)) + "\n```\n\n(" + decl.Kind + ") " + decl.Name.Lexeme
+	} else if doc, ok := interpreter.Docs[name.Lexeme]; ok {
+		value = "```friston\n" + doc.Signature + "\n```\n\n" + doc.Text
+	} else {
+		return nil
+	}
+	return Hover{markupContent{"markdown", value}, &tokRange}
+}
+
+// Completes keywords, natives, constants and the names in scope at a position. Properties aren't known, so
+// nothing is completed after a '.'.
+func (d *document) completion(pos Position) []CompletionItem {
+	items := []CompletionItem{}
+	line, column := d.column(pos)
+	if line <= len(d.lines) {
+		before := strings.TrimRight(d.lines[line-1][:column-1], "_abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+		if strings.HasSuffix(before, ".") {
+			return items
+		}
+	}
+
+	seen := map[string]bool{}
+	add := func(item CompletionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	// Inner scopes come first, so their names hide outer ones.
+	decls := d.symbols.Declarations
+	for n := len(decls) - 1; n >= 0; n-- {
+		decl := decls[n]
+		global := decl.Scope.Parent == nil
+		if !d.covers(decl.Scope, line, column) || (!global && decl.Name.Line > line) {
+			continue
+		}
+
+		kind := completionVariable
+		switch decl.Kind {
+		case "function":
+			kind = completionFunction
+		case "import":
+			kind = completionModule
+		}
+		add(CompletionItem{decl.Name.Lexeme, kind, decl.Kind})
+	}
+
+	var natives []string
+	for name := range interpreter.Natives {
+		natives = append(natives, name)
+	}
+	for name := range interpreter.Constants {
+		natives = append(natives, name)
+	}
+	sort.Strings(natives)
+	for _, name := range natives {
+		kind := completionFunction
+		if _, ok := interpreter.Constants[name]; ok {
+			kind = completionConstant
+		}
+		add(CompletionItem{name, kind, interpreter.Docs[name].Signature})
+	}
+
+	for _, keyword := range lexer.Keywords() {
+		add(CompletionItem{keyword, completionKeyword, "keyword"})
+	}
+	return items
+}
+
+// Whether a scope covers a line. A scope ends on the line its last statement starts on, but while typing at
+// the end of a block, the lines after it are still in it until one is indented less than its first statement.
+func (d *document) covers(scope *lint.Scope, line int, column int) bool {
+	if scope.Parent == nil {
+		return true
+	}
+	if line < scope.Start || scope.Body == 0 {
+		return false
+	}
+	if line <= scope.End {
+		return true
+	}
+
+	body := indentation(d.line(scope.Body))
+	for n := scope.End + 1; n <= line && n <= len(d.lines); n++ {
+		text := d.line(n)
+		indent := indentation(text)
+		if blank := indent == len(text); blank && n == line {
+			// A blank line being typed on is indented up to the cursor.
+			indent = column - 1
+		} else if blank {
+			continue
+		}
+		if indent < body {
+			return false
+		}
+	}
+	return true
+}
+
+func indentation(text string) int {
+	return len(text) - len(strings.TrimLeft(text, " \t"))
+}
+
+// Lists function declarations, with nested functions as their children.
+func (d *document) outline() []DocumentSymbol {
+	// Symbols are built as a tree of pointers, as children are found after their parents are added.
+	type node struct {
+		symbol   DocumentSymbol
+		children []*node
+	}
+	root := &node{}
+	bodies := map[*lint.Scope]*node{}
+
+	for _, decl := range d.symbols.Declarations {
+		if decl.Kind != "function" {
+			continue
+		}
+
+		end := d.position(decl.Body.End, len(d.line(decl.Body.End))+1)
+		n := &node{symbol: DocumentSymbol{
+			Name:           decl.Name.Lexeme,
+			Detail:         strings.TrimSpace(d.line(decl.Name.Line)),
+			Kind:           symbolFunction,
+			Range:          Range{d.position(decl.Name.Line, 1), end},
+			SelectionRange: d.tokenRange(decl.Name),
+		}}
+		bodies[decl.Body] = n
+
+		// A function's parent is the nearest function whose body encloses it.
+		parent := root
+		for scope := decl.Scope; scope != nil; scope = scope.Parent {
+			if p, ok := bodies[scope]; ok {
+				parent = p
+				break
+			}
+		}
+		parent.children = append(parent.children, n)
+	}
+
+	var build func(nodes []*node) []DocumentSymbol
+	build = func(nodes []*node) []DocumentSymbol {
+		symbols := []DocumentSymbol{}
+		for _, n := range nodes {
+			symbol := n.symbol
+			if len(n.children) > 0 {
+				symbol.Children = build(n.children)
+			}
+			symbols = append(symbols, symbol)
+		}
+		return symbols
+	}
+	return build(root.children)
+}
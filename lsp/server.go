@@ -0,0 +1,224 @@
+// Package lsp is a Language Server Protocol server for friston, which editors run over stdio.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Serves one editor, keeping the open documents.
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string]*document
+	// Set by the shutdown request, before the editor sends exit.
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, docs: map[string]*document{}}
+}
+
+// Handles messages until the exit notification or the end of the input. Returns an error if the editor
+// exits without a shutdown request, or the input is broken.
+func (s *Server) Serve() error {
+	for {
+		body, err := s.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.respond(nil, nil, &responseError{parseError, err.Error()})
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exited without a shutdown request")
+			}
+			return nil
+		}
+		s.handle(req)
+	}
+}
+
+// Reads a message's body, which follows a Content-Length header.
+func (s *Server) read() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("bad Content-Length: %v", err)
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(s.in, body)
+	return body, err
+}
+
+func (s *Server) write(message interface{}) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *Server) respond(id json.RawMessage, result interface{}, err *responseError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	res := response{JSONRPC: "2.0", ID: id, Error: err}
+	if err == nil {
+		raw, _ := json.Marshal(result)
+		res.Result = (*json.RawMessage)(&raw)
+	}
+	s.write(res)
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	s.write(notification{"2.0", method, params})
+}
+
+// Runs a request's handler and responds with its result. Notifications don't get a response.
+func (s *Server) handle(req request) {
+	isRequest := req.ID != nil
+
+	defer func() {
+		if r := recover(); r != nil {
+			if isRequest {
+				s.respond(req.ID, nil, &responseError{internalError, fmt.Sprint(r)})
+			}
+		}
+	}()
+
+	result, err := s.dispatch(req)
+	if isRequest {
+		s.respond(req.ID, result, err)
+	}
+}
+
+func (s *Server) dispatch(req request) (interface{}, *responseError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":       1,
+				"definitionProvider":     true,
+				"referencesProvider":     true,
+				"hoverProvider":          true,
+				"documentSymbolProvider": true,
+				"completionProvider":     map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "friston"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{params.TextDocument.URI, []Diagnostic{}})
+
+	case "textDocument/definition":
+		var params positionParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		if doc, ok := s.docs[params.TextDocument.URI]; ok {
+			return doc.definition(params.TextDocument.URI, params.Position), nil
+		}
+		return nil, nil
+	case "textDocument/references":
+		var params referenceParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		if doc, ok := s.docs[params.TextDocument.URI]; ok {
+			return doc.references(params.TextDocument.URI, params.Position, params.Context.IncludeDeclaration), nil
+		}
+		return []Location{}, nil
+	case "textDocument/hover":
+		var params positionParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		if doc, ok := s.docs[params.TextDocument.URI]; ok {
+			return doc.hover(params.Position), nil
+		}
+		return nil, nil
+	case "textDocument/completion":
+		var params positionParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		if doc, ok := s.docs[params.TextDocument.URI]; ok {
+			return doc.completion(params.Position), nil
+		}
+		return []CompletionItem{}, nil
+	case "textDocument/documentSymbol":
+		var params documentSymbolParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		if doc, ok := s.docs[params.TextDocument.URI]; ok {
+			return doc.outline(), nil
+		}
+		return []DocumentSymbol{}, nil
+
+	default:
+		// Notifications the server doesn't use (ex: "initialized", "$/cancelRequest") are ignored.
+		if req.ID != nil && !strings.HasPrefix(req.Method, "$/") {
+			return nil, &responseError{methodNotFound, "Unknown method '" + req.Method + "'."}
+		}
+	}
+	return nil, nil
+}
+
+func decode(req request, params interface{}) *responseError {
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		return &responseError{invalidParams, err.Error()}
+	}
+	return nil
+}
+
+// Analyzes a document's new text, and publishes its syntax errors.
+func (s *Server) update(uri string, text string) {
+	doc := newDocument(text, s.docs[uri])
+	s.docs[uri] = doc
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{uri, doc.diagnostics()})
+}
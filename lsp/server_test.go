@@ -0,0 +1,197 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"strconv"
+	"testing"
+)
+
+const uri = "file:///tmp/test.fn"
+
+// Runs the server over the messages, and returns the messages it wrote.
+func serve(t *testing.T, messages ...interface{}) []map[string]interface{} {
+	t.Helper()
+
+	var in bytes.Buffer
+	for _, message := range messages {
+		body, _ := json.Marshal(message)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	var out bytes.Buffer
+	if err := NewServer(&in, &out).Serve(); err != nil {
+		t.Fatalf("Serve() = %v", err)
+	}
+
+	var written []map[string]interface{}
+	reader := bufio.NewReader(&out)
+	for {
+		header, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if err == io.EOF {
+			return written
+		} else if err != nil {
+			t.Fatalf("bad header: %v", err)
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			t.Fatalf("short body: %v", err)
+		}
+
+		var message map[string]interface{}
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Fatalf("bad body %s: %v", body, err)
+		}
+		written = append(written, message)
+	}
+}
+
+func call(id int, method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notify(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func open(text string) map[string]interface{} {
+	return notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "friston", "version": 1, "text": text},
+	})
+}
+
+func position(line int, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+
+// Returns the response to the request with id.
+func responseTo(t *testing.T, messages []map[string]interface{}, id int) map[string]interface{} {
+	t.Helper()
+	for _, message := range messages {
+		if message["id"] == float64(id) {
+			return message
+		}
+	}
+	t.Fatalf("no response to request %d in %v", id, messages)
+	return nil
+}
+
+// Returns the diagnostics of the last publishDiagnostics notification.
+func diagnostics(t *testing.T, messages []map[string]interface{}) []interface{} {
+	t.Helper()
+	var diagnostics []interface{}
+	found := false
+	for _, message := range messages {
+		if message["method"] == "textDocument/publishDiagnostics" {
+			params := message["params"].(map[string]interface{})
+			if params["uri"] != uri {
+				t.Fatalf("diagnostics for %v, want %s", params["uri"], uri)
+			}
+			diagnostics = params["diagnostics"].([]interface{})
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("no diagnostics in %v", messages)
+	}
+	return diagnostics
+}
+
+func TestInitialize(t *testing.T) {
+	messages := serve(t,
+		call(1, "initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}),
+		notify("initialized", map[string]interface{}{}),
+		call(2, "shutdown", nil),
+		notify("exit", nil),
+	)
+
+	result := responseTo(t, messages, 1)["result"].(map[string]interface{})
+	capabilities := result["capabilities"].(map[string]interface{})
+	if capabilities["definitionProvider"] != true || capabilities["textDocumentSync"] != float64(1) {
+		t.Fatalf("capabilities = %v", capabilities)
+	}
+	if res := responseTo(t, messages, 2); res["error"] != nil {
+		t.Fatalf("shutdown = %v", res)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	in := bytes.NewBufferString("Content-Length: 33\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"exit\"}")
+	if err := NewServer(in, ioutil.Discard).Serve(); err == nil {
+		t.Fatal("Serve() = nil, want an error")
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	messages := serve(t, open("let x = 1\n"))
+	if d := diagnostics(t, messages); len(d) != 0 {
+		t.Fatalf("diagnostics = %v, want none", d)
+	}
+
+	messages = serve(t, open("let x = (1\nprintln(x)\n"))
+	d := diagnostics(t, messages)
+	if len(d) == 0 {
+		t.Fatal("no diagnostics for a syntax error")
+	}
+	diagnostic := d[0].(map[string]interface{})
+	if diagnostic["severity"] != float64(1) || diagnostic["message"] == "" {
+		t.Fatalf("diagnostic = %v", diagnostic)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	src := "let total = 1\n" +
+		"function add: n =\n" +
+		"    return total + n\n" +
+		"println(add(2))\n"
+
+	messages := serve(t,
+		open(src),
+		call(1, "textDocument/definition", position(2, 12)),
+		call(2, "textDocument/definition", position(3, 9)),
+		call(3, "textDocument/definition", position(2, 20)),
+		call(4, "textDocument/definition", position(3, 0)),
+	)
+
+	tests := []struct {
+		id        int
+		line      float64
+		character float64
+	}{
+		{1, 0, 4},  // total
+		{2, 1, 9},  // add
+		{3, 1, 14}, // n
+	}
+	for _, test := range tests {
+		result, ok := responseTo(t, messages, test.id)["result"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("definition %d = %v", test.id, responseTo(t, messages, test.id))
+		}
+		start := result["range"].(map[string]interface{})["start"].(map[string]interface{})
+		if result["uri"] != uri || start["line"] != test.line || start["character"] != test.character {
+			t.Errorf("definition %d = %v, want line %v character %v", test.id, result, test.line, test.character)
+		}
+	}
+
+	// println is a native, so it has no definition.
+	if result := responseTo(t, messages, 4)["result"]; result != nil {
+		t.Errorf("definition of a native = %v, want null", result)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	messages := serve(t, call(1, "textDocument/unknown", map[string]interface{}{}))
+	err, ok := responseTo(t, messages, 1)["error"].(map[string]interface{})
+	if !ok || err["code"] != float64(methodNotFound) {
+		t.Fatalf("response = %v, want a methodNotFound error", responseTo(t, messages, 1))
+	}
+}
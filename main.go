@@ -2,109 +2,427 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"friston/ast"
+	"friston/errors"
 	"friston/interpreter"
 	"friston/lexer"
+	"friston/lint"
+	"friston/lsp"
 	"friston/parser"
 	"friston/type_generator"
 	"friston/visitors"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-// Gets arguments when using 'go run *.go -- ...'
+const version = "0.1.0"
+
+// Stdin is read through one buffered reader, shared by the REPL's line editor, the debugger and programs'
+// readLine(), so none of them buffers input meant for another.
+var stdin = bufio.NewReader(os.Stdin)
+
+// Exit codes, from BSD's sysexits.h.
+const (
+	exitOK           = 0
+	exitUsage        = 64
+	exitSyntaxError  = 65
+	exitNoInput      = 66
+	exitRuntimeError = 70
+)
+
+const usage = `Usage:
+  friston <file> [args...]       run a program
+  friston run <file> [args...]   run a program, even if its name is a command
+  friston -e <code> [args...]    run code from the command line
+  friston repl                   start an interactive session (the default)
+  friston tokens <file>          print the tokens of a program
+  friston ast <file>             print the syntax tree of a program
+  friston check <files...>       check programs for syntax errors
+  friston debug <file> [args...] run a program in a step debugger, which reads commands from stdin
+  friston fmt [-check|-w] <files...>
+                                 format programs, printing them, listing unformatted
+                                 files (exiting with 1 if there are any) or rewriting them
+  friston lint [-json] [-enable rules] [-disable rules] <files...>
+                                 check programs for likely mistakes, exiting with 1 if
+                                 any are found (rules are comma separated)
+  friston lint -rules            list the lint rules
+  friston lsp                    start a language server for editors, over stdio
+  friston dap [-listen addr]     start a debug adapter for editors, over stdio or on a TCP
+                                 address (ex: localhost:4711)
+  friston --help                 show this help
+  friston --version              show the version
+
+A file of '-' reads the program from stdin. A program's arguments are in the 'args' global.
+Programs exit with the code given to exit(), or 64 for usage errors, 65 for syntax errors,
+66 for missing files and 70 for runtime errors.`
+
 func main() {
+	os.Exit(cli(os.Args[1:]))
+}
 
-	var args []string
-	if len(os.Args) > 2 {
-		args = os.Args[2:]
+// Runs a command, and returns the exit code.
+func cli(args []string) int {
+	// Arguments after 'go run . --' start with the "--".
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
 	}
-
-	if len(args) >= 1 && args[0] == "repl" {
+	if len(args) == 0 {
 		repl()
-	} else if len(args) >= 3 && args[0] == "file" && args[2] == "-v" {
-		file(args[1], false)
-	} else if len(args) >= 2 && args[0] == "file" {
-		file(args[1], true)
-	} else if len(args) >= 2 && args[0] == "GenASTSource" {
-		genASTSource(args[1])
-	} else {
+		return exitOK
+	}
+
+	command, rest := args[0], args[1:]
+	switch command {
+	case "-h", "-help", "--help", "help":
+		fmt.Println(usage)
+		return exitOK
+	case "-version", "--version", "version":
+		fmt.Println("friston " + version)
+		return exitOK
+	case "repl":
 		repl()
+		return exitOK
+	case "-e":
+		if len(rest) == 0 {
+			return usageError("-e needs code to run.")
+		}
+		return runSource(rest[0], "<command line>", "", rest[1:])
+	case "run":
+		if len(rest) == 0 {
+			return usageError("run needs a file.")
+		}
+		return runFile(rest[0], rest[1:])
+	case "tokens", "ast":
+		if len(rest) != 1 {
+			return usageError(command + " needs one file.")
+		}
+		return printFile(command, rest[0])
+	case "check":
+		if len(rest) == 0 {
+			return usageError("check needs at least one file.")
+		}
+		return checkFiles(rest)
+	case "debug":
+		if len(rest) == 0 {
+			return usageError("debug needs a file.")
+		}
+		return debugFile(rest[0], rest[1:])
+	case "dap":
+		return serveDAP(rest)
+	case "fmt":
+		return formatFiles(rest)
+	case "lint":
+		return lintFiles(rest)
+	case "lsp":
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintf(os.Stderr, "lsp: %v\n", err)
+			return 1
+		}
+		return exitOK
+	case "GenASTSource":
+		if len(rest) == 0 {
+			return usageError("GenASTSource needs a file.")
+		}
+		genASTSource(rest[0])
+		return exitOK
 	}
+
+	// A bare path runs the file, so scripts can start with "#!/usr/bin/env friston", and "-" runs stdin.
+	if command == "-" || strings.HasSuffix(command, ".fn") || isFile(command) {
+		return runFile(command, rest)
+	}
+
+	return usageError(fmt.Sprintf("Unknown command '%s'.", command))
 }
 
-// Helper function to check for errors when reading files
-func check(err error) {
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func usageError(message string) int {
+	fmt.Fprintf(os.Stderr, "%s\n\n%s\n", message, usage)
+	return exitUsage
+}
+
+// Reads a program from a file, or stdin if path is "-".
+func readSource(path string) (string, error) {
+	if path == "-" {
+		src, err := ioutil.ReadAll(stdin)
+		return string(src), err
+	}
+
+	src, err := ioutil.ReadFile(path)
+	return string(src), err
+}
+
+// Reads a program and returns its source, or the exit code if it can't be read.
+func readProgram(path string) (string, int) {
+	src, err := readSource(path)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "Can't read '%s': %v\n", path, err)
+		return "", exitNoInput
+	}
+	return src, exitOK
+}
+
+func runFile(path string, args []string) int {
+	src, code := readProgram(path)
+	if code != exitOK {
+		return code
+	}
+
+	if path == "-" {
+		return runSource(src, "<stdin>", "", args)
 	}
+	return runSource(src, filepath.Base(path), filepath.Dir(path), args)
 }
 
-// TODO: Implement a REPL
+// Runs a program with its name (for stack traces), directory (for imports) and arguments.
+func runSource(src string, script string, dir string, args []string) int {
+	stmts, ok := parse(src, false, os.Stderr)
+	if !ok {
+		return exitSyntaxError
+	}
 
-func repl() {
-	fmt.Printf("Entering REPL:\n>>> ")
+	inter := newInterpreter(false, args)
+	inter.Script = script
+	inter.Dir = dir
 
-	scanner := bufio.NewScanner(os.Stdin)
+	err := inter.Interpret(stmts)
+	if exit, ok := err.(errors.ExitError); ok {
+		return exit.Code
+	} else if err != nil {
+		return exitRuntimeError
+	}
+	return exitOK
+}
 
-	inter := interpreter.NewInterpreter(true)
+// Creates an interpreter with every capability, and the program's arguments as the 'args' list.
+func newInterpreter(repl bool, args []string) interpreter.Interpreter {
+	inter := interpreter.NewInterpreter(repl, os.Stdout, os.Stderr, stdin, interpreter.AllCapabilities)
+	inter.SearchPath = searchPath()
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	list := &interpreter.List{}
+	for _, arg := range args {
+		list.Elements = append(list.Elements, arg)
+	}
+	inter.Define("args", list)
 
-		if line == "exit" {
-			os.Exit(0)
-		}
+	return inter
+}
+
+// Prints the tokens or syntax tree of a program.
+func printFile(command string, path string) int {
+	src, code := readProgram(path)
+	if code != exitOK {
+		return code
+	}
 
-		lex := lexer.NewLexer(line, true)
+	if command == "tokens" {
+		lex := lexer.NewLexer(src, false)
+		lex.Errors = os.Stderr
 		tokens, lexErr := lex.ScanTokens()
+		if lexErr {
+			return exitSyntaxError
+		}
+		lexer.PrintTokens(tokens)
+		return exitOK
+	}
+
+	stmts, ok := parse(src, false, os.Stderr)
+	if !ok {
+		return exitSyntaxError
+	}
+	printer := visitors.ASTPrinter{}
+	for _, s := range stmts {
+		s.Accept(printer)
+	}
+	fmt.Println()
+	return exitOK
+}
 
-		if !lexErr {
-			par := parser.NewParser(tokens)
-			stmts, parErr := par.Parse()
+// Checks each file for syntax errors, which are listed under the file's path.
+func checkFiles(paths []string) int {
+	exit := exitOK
+	for _, path := range paths {
+		src, code := readProgram(path)
+		if code != exitOK {
+			exit = code
+			continue
+		}
 
-			if !parErr {
-				inter.Interpret(stmts)
-				fmt.Printf(">>> ")
+		var errs bytes.Buffer
+		if _, ok := parse(src, false, &errs); !ok {
+			fmt.Fprintf(os.Stderr, "%s:\n%s", path, errs.String())
+			if exit == exitOK {
+				exit = exitSyntaxError
 			}
 		}
 	}
+	return exit
 }
 
-// Reads file into lexer, tokenizes, and prints tokens
-func file(path string, quiet bool) {
-	dat, err := ioutil.ReadFile(path)
-	check(err)
+// Formats each file, printing the result, or with -check listing the files that aren't formatted,
+// or with -w rewriting them.
+func formatFiles(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	checkOnly := flags.Bool("check", false, "")
+	write := flags.Bool("w", false, "")
+	if err := flags.Parse(args); err != nil {
+		return usageError("fmt: " + err.Error() + ".")
+	}
+	if flags.NArg() == 0 {
+		return usageError("fmt needs at least one file.")
+	}
+	if *checkOnly && *write {
+		return usageError("fmt can't use -check and -w together.")
+	}
+
+	exit := exitOK
+	for _, path := range flags.Args() {
+		src, code := readProgram(path)
+		if code != exitOK {
+			exit = code
+			continue
+		}
+
+		var errs bytes.Buffer
+		formatted, ok := visitors.Format(src, &errs)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s:\n%s", path, errs.String())
+			exit = exitSyntaxError
+			continue
+		}
 
-	if !quiet {
-		fmt.Println(path + ":" + "\n")
-		fmt.Println(string(dat) + "\n")
+		switch {
+		case *checkOnly:
+			if formatted != src {
+				fmt.Println(path)
+				if exit == exitOK {
+					exit = 1
+				}
+			}
+		case *write && path != "-":
+			if formatted != src {
+				if err := ioutil.WriteFile(path, []byte(formatted), 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "Can't write '%s': %v\n", path, err)
+					exit = exitRuntimeError
+				}
+			}
+		default:
+			fmt.Print(formatted)
+		}
 	}
+	return exit
+}
 
-	lex := lexer.NewLexer(string(dat), false)s
-	tokens, lexErr := lex.ScanTokens()
+// Lints each file, printing diagnostics as "file:line:col: message (rule)", or as a JSON list with -json.
+func lintFiles(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	asJSON := flags.Bool("json", false, "")
+	listRules := flags.Bool("rules", false, "")
+	enable := flags.String("enable", "", "")
+	disable := flags.String("disable", "", "")
+	if err := flags.Parse(args); err != nil {
+		return usageError("lint: " + err.Error() + ".")
+	}
 
-	if !lexErr {
-		if !quiet {
-			lexer.PrintTokens(tokens)
+	if *listRules {
+		for _, rule := range lint.Rules {
+			fmt.Printf("%-20s %s\n", rule.Name, rule.Description)
 		}
+		return exitOK
+	}
+	if flags.NArg() == 0 {
+		return usageError("lint needs at least one file.")
+	}
 
-		par := parser.NewParser(tokens)
-		stmts, parErr := par.Parse()
+	config := lint.Config{Enable: ruleList(*enable), Disable: ruleList(*disable), Globals: []string{"args"}}
 
-		if !quiet && !parErr {
-			printer := visitors.ASTPrinter{}
-			fmt.Printf("\n")
-			for _, s := range stmts {
-				s.Accept(printer)
-			}
-			fmt.Printf("\n")
+	exit := exitOK
+	diagnostics := []lint.Diagnostic{}
+	for _, path := range flags.Args() {
+		src, code := readProgram(path)
+		if code != exitOK {
+			exit = code
+			continue
+		}
+
+		var errs bytes.Buffer
+		stmts, ok := parse(src, false, &errs)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s:\n%s", path, errs.String())
+			exit = exitSyntaxError
+			continue
 		}
 
-		if !parErr {
-			inter := interpreter.NewInterpreter(false)
-			inter.Interpret(stmts)
+		found, err := lint.Lint(path, stmts, config)
+		if err != nil {
+			return usageError(err.Error())
 		}
+		diagnostics = append(diagnostics, found...)
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(diagnostics, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		for _, d := range diagnostics {
+			fmt.Println(d)
+		}
+	}
+
+	if len(diagnostics) > 0 && exit == exitOK {
+		exit = 1
+	}
+	return exit
+}
+
+// Splits a comma separated list of rules.
+func ruleList(rules string) []string {
+	var list []string
+	for _, rule := range strings.Split(rules, ",") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			list = append(list, rule)
+		}
+	}
+	return list
+}
+
+// Lexes and parses source, reporting syntax errors to errs. In REPL mode, tokens don't have lines.
+func parse(src string, repl bool, errs io.Writer) ([]ast.Statement, bool) {
+	lex := lexer.NewLexer(src, repl)
+	lex.Errors = errs
+	tokens, lexErr := lex.ScanTokens()
+	if lexErr {
+		return nil, false
+	}
+
+	par := parser.NewParser(tokens)
+	par.Errors = errs
+	stmts, parErr := par.Parse()
+	return stmts, !parErr
+}
+
+// Directories to import modules from, listed in the FRISTON_PATH environment variable.
+func searchPath() []string {
+	return filepath.SplitList(os.Getenv("FRISTON_PATH"))
+}
+
+// Helper function to check for errors when reading files
+func check(err error) {
+	if err != nil {
+		panic(err)
 	}
 }
 
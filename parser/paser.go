@@ -4,6 +4,8 @@ import (
 	"friston/ast"
 	"friston/errors"
 	"friston/lexer"
+	"io"
+	"os"
 )
 
 type parser struct {
@@ -11,6 +13,11 @@ type parser struct {
 	current int
 	statements []ast.Statement
 	errFlag bool
+	// Destination of parse errors, defaults to os.Stderr so they stay out of program output. They are also
+	// collected in SyntaxErrors, like the lexer's.
+	Errors io.Writer
+	// Every error reported, with its position.
+	SyntaxErrors []errors.SyntaxError
 }
 
 // Parser constructor, initializes default vaules
@@ -18,6 +25,7 @@ func NewParser(tokens []lexer.Token) parser {
 	p := parser{}
 	p.tokens = tokens
 	p.current = 0
+	p.Errors = os.Stderr
 
 	return p
 }
@@ -36,6 +44,14 @@ func (p *parser) peek() lexer.Token {
 	return p.tokens[p.current]
 }
 
+// Return the token after the current one without consuming anything.
+func (p *parser) peekNext() lexer.Token {
+	if p.isAtEnd() {
+		return p.peek()
+	}
+	return p.tokens[p.current + 1]
+}
+
 // Check if the current position is the last token (an EOF token).
 func (p *parser) isAtEnd() bool {
 	return p.peek().TType == lexer.EOF
@@ -95,40 +111,40 @@ func (p *parser) assignment() ast.Expression {
 		vr, ok := expr.(ast.Variable)
 		if ok {
 			name := vr.Name
-			return ast.Assignment{name, value}
+			return ast.Assignment{Name: name, Value: value}
 		}
 
-		errors.ThrowError(equals.Line, "Invalid assignment target.")
+		p.report(equals, "Invalid assignment target.")
 	}
 
 	// Implement increment (++) and decrement (--) as sugar, ex: translate a++ to a = a + 1
 	if p.match([]lexer.TokenType{lexer.PLUS_PLUS}) {
-		operator := lexer.Token{lexer.PLUS, "+", nil, p.previous().Line}
-		right := ast.Literal{lexer.Token{lexer.NUMBER, "1", 1.0, p.previous().Line}}
-		binary := ast.Binary{expr, operator, right}
+		operator := lexer.Token{TType: lexer.PLUS, Lexeme: "+", Line: p.previous().Line}
+		right := ast.Literal{X: lexer.Token{TType: lexer.NUMBER, Lexeme: "1", Literal: 1.0, Line: p.previous().Line}}
+		binary := ast.Binary{X: expr, Op: operator, Y: right}
 
 		vr, ok := expr.(ast.Variable)
 		if ok {
 			name := vr.Name
-			return ast.Assignment{name, binary}
+			return ast.Assignment{Name: name, Value: binary, Postfix: p.previous()}
 		}
 
-		errors.ThrowError(p.previous().Line, "Invalid increment target.")
+		p.report(p.previous(), "Invalid increment target.")
 	}
 
 	// Decrement
 	if p.match([]lexer.TokenType{lexer.MINUS_MINUS}) {
-		operator := lexer.Token{lexer.MINUS, "-", nil, p.previous().Line}
-		right := ast.Literal{lexer.Token{lexer.NUMBER, "1", 1.0, p.previous().Line}}
-		binary := ast.Binary{expr, operator, right}
+		operator := lexer.Token{TType: lexer.MINUS, Lexeme: "-", Line: p.previous().Line}
+		right := ast.Literal{X: lexer.Token{TType: lexer.NUMBER, Lexeme: "1", Literal: 1.0, Line: p.previous().Line}}
+		binary := ast.Binary{X: expr, Op: operator, Y: right}
 
 		vr, ok := expr.(ast.Variable)
 		if ok {
 			name := vr.Name
-			return ast.Assignment{name, binary}
+			return ast.Assignment{Name: name, Value: binary, Postfix: p.previous()}
 		}
 
-		errors.ThrowError(p.previous().Line, "Invalid decrement target.")
+		p.report(p.previous(), "Invalid decrement target.")
 	}
 
 	return expr
@@ -140,7 +156,7 @@ func (p *parser) or() ast.Expression {
 	for p.match([]lexer.TokenType{lexer.OR}) {
 		operator := p.previous()
 		value := p.and()
-		expr = ast.Logic{expr, operator, value}
+		expr = ast.Logic{X: expr, Op: operator, Y: value}
 	}
 
 	return expr
@@ -152,7 +168,7 @@ func (p *parser) and() ast.Expression {
 	for p.match([]lexer.TokenType{lexer.AND}) {
 		operator := p.previous()
 		value := p.equality()
-		expr = ast.Logic{expr, operator, value}
+		expr = ast.Logic{X: expr, Op: operator, Y: value}
 	}
 
 	return expr
@@ -164,7 +180,7 @@ func (p *parser) equality() ast.Expression {
 	for p.match([]lexer.TokenType{lexer.BANG_EQUAL, lexer.EQUAL_EQUAL}) {
 		operator := p.previous()
 		right := p.comparison()
-		expr = ast.Binary{expr, operator, right}
+		expr = ast.Binary{X: expr, Op: operator, Y: right}
 	}
 
 	return expr
@@ -176,7 +192,7 @@ func (p *parser) comparison() ast.Expression {
 	for p.match([]lexer.TokenType{lexer.LESS, lexer.LESS_EQUAL, lexer.GREATER, lexer.GREATER_EQUAL}) {
 		operator := p.previous()
 		right := p.addition()
-		expr = ast.Binary{expr, operator, right}
+		expr = ast.Binary{X: expr, Op: operator, Y: right}
 	}
 
 	return expr
@@ -188,7 +204,7 @@ func (p *parser) addition() ast.Expression {
 	for p.match([]lexer.TokenType{lexer.PLUS, lexer.MINUS}) {
 		operator := p.previous()
 		right := p.multiplication()
-		expr = ast.Binary{expr, operator, right}
+		expr = ast.Binary{X: expr, Op: operator, Y: right}
 	}
 
 	return expr
@@ -200,7 +216,7 @@ func (p *parser) multiplication() ast.Expression {
 	for p.match([]lexer.TokenType{lexer.STAR, lexer.SLASH}) {
 		operator := p.previous()
 		right := p.unary()
-		expr = ast.Binary{expr, operator, right}
+		expr = ast.Binary{X: expr, Op: operator, Y: right}
 	}
 
 	return expr
@@ -210,7 +226,7 @@ func (p *parser) unary() ast.Expression {
 	if p.match([]lexer.TokenType{lexer.BANG, lexer.MINUS}) {
 		operator := p.previous()
 		right := p.unary()
-		return ast.Unary{operator, right}
+		return ast.Unary{Op: operator, X: right}
 	}
 
 	return p.call()
@@ -218,44 +234,93 @@ func (p *parser) unary() ast.Expression {
 
 func (p *parser) call() ast.Expression {
 	expr := p.primary()
-	
+
+	// Calls, property accesses and indexes can be chained, ex: error.trace, makeCounter()() or rows[0][1]
+	for {
+		if p.match([]lexer.TokenType{lexer.LEFT_PAREN}) {
+			expr = p.finishCall(expr)
+		} else if p.match([]lexer.TokenType{lexer.LEFT_BRACKET}) {
+			bracket := p.previous()
+			index := p.expression()
+			p.consume(lexer.RIGHT_BRACKET, "Expect ']' after index.")
+			expr = ast.Index{Object: expr, Bracket: bracket, Index: index}
+		} else if p.match([]lexer.TokenType{lexer.DOT}) {
+			p.consume(lexer.IDENTIFIER, "Expect property name after '.'.")
+			expr = ast.Get{Object: expr, Name: p.previous()}
+		} else {
+			return expr
+		}
+	}
+}
+
+func (p *parser) finishCall(callee ast.Expression) ast.Expression {
+	paren := p.previous()
+
 	var arguments []ast.Expression
-	if p.match([]lexer.TokenType{lexer.LEFT_PAREN}) {
-		paren := p.previous()
-
-		for !p.check(lexer.RIGHT_PAREN) {
-			arg := p.expression()
-			arguments = append(arguments, arg)
-			if p.peek().TType != lexer.RIGHT_PAREN {
-				p.consume(lexer.COMMA, "Arguments must be separated by ','.")
+	var keywords []ast.KeywordArg
+	for !p.check(lexer.RIGHT_PAREN) && !p.isAtEnd() {
+		// Keyword arguments are a name followed by ':', ex: scale: 2
+		if p.check(lexer.IDENTIFIER) && p.peekNext().TType == lexer.COLON {
+			name := p.advance()
+			p.advance()
+			for _, keyword := range keywords {
+				if keyword.Name.Lexeme == name.Lexeme {
+					p.report(name, "Keyword argument '" + name.Lexeme + "' is given more than once.")
+				}
 			}
+			keywords = append(keywords, ast.KeywordArg{Name: name, Value: p.expression()})
+		} else {
+			if len(keywords) > 0 {
+				p.report(p.peek(), "Positional arguments must come before keyword arguments.")
+			}
+			arguments = append(arguments, p.expression())
+		}
+
+		if p.peek().TType != lexer.RIGHT_PAREN {
+			p.consume(lexer.COMMA, "Arguments must be separated by ','.")
 		}
-		p.consume(lexer.RIGHT_PAREN, "Arguments must end with ')'.")
-		return ast.Call{expr, paren, arguments}
 	}
+	p.consume(lexer.RIGHT_PAREN, "Arguments must end with ')'.")
 
-	return expr
+	return ast.Call{Callee: callee, Paren: paren, Arguments: arguments, Keywords: keywords}
 }
 
 func (p *parser) primary() ast.Expression {
 	if p.match([]lexer.TokenType{lexer.TRUE, lexer.FALSE, lexer.NIL}) {
-		return ast.Literal{p.previous()}
+		return ast.Literal{X: p.previous()}
 	} else if p.match([]lexer.TokenType{lexer.NUMBER, lexer.STRING}) {
-		return ast.Literal{p.previous()}
+		return ast.Literal{X: p.previous()}
 	} else if p.match([]lexer.TokenType{lexer.LEFT_PAREN}) {
 		left := p.previous()
 		expr := p.expression()
 		p.consume(lexer.RIGHT_PAREN, "Expect ')' after expression.")
 		right := p.previous()
-		return ast.Group{left, expr, right}
+		return ast.Group{Left: left, X: expr, Right: right}
 	} else if p.match([]lexer.TokenType{lexer.IDENTIFIER}) {
-		return ast.Variable{p.previous()}
+		return ast.Variable{Name: p.previous()}
+	} else if p.match([]lexer.TokenType{lexer.LEFT_BRACKET}) {
+		return p.listLiteral()
 	} else {
 		p.parseError(p.peek(), "Expect expression.")
 		return nil
 	}
 }
 
+func (p *parser) listLiteral() ast.Expression {
+	bracket := p.previous()
+
+	var elements []ast.Expression
+	for !p.check(lexer.RIGHT_BRACKET) && !p.isAtEnd() {
+		elements = append(elements, p.expression())
+		if p.peek().TType != lexer.RIGHT_BRACKET {
+			p.consume(lexer.COMMA, "List elements must be separated by ','.")
+		}
+	}
+	p.consume(lexer.RIGHT_BRACKET, "Lists must end with ']'.")
+
+	return ast.ListLiteral{Bracket: bracket, Elements: elements}
+}
+
 // Statement/Declaration creator methods:
 
 func (p *parser) declaration() ast.Statement {
@@ -276,9 +341,31 @@ func (p *parser) funcDecl() ast.Statement {
 	p.consume(lexer.COLON, "Expect ':' in function declaration.")
 
 	var parameters []lexer.Token
-	for !p.check(lexer.EQUAL) {
+	var defaults []ast.Expression
+	rest := false
+	for !p.check(lexer.EQUAL) && !p.isAtEnd() {
+		if rest {
+			p.report(p.peek(), "The '...' parameter must be the last parameter.")
+		}
+		rest = p.match([]lexer.TokenType{lexer.DOT_DOT_DOT})
+
 		param := p.advance()
 		parameters = append(parameters, param)
+
+		// A parameter is followed by a default value, unless its '=' ends the parameters, ex: function f: x, scale = 2 =
+		var def ast.Expression
+		if p.check(lexer.EQUAL) && p.peekNext().TType != lexer.INDENT {
+			p.advance()
+			def = p.or()
+			if rest {
+				p.report(param, "The '...' parameter can't have a default value.")
+			}
+		}
+		if def == nil && !rest && len(defaults) > 0 && defaults[len(defaults)-1] != nil {
+			p.report(param, "Parameters without default values must come before parameters with them.")
+		}
+		defaults = append(defaults, def)
+
 		if p.peek().TType != lexer.EQUAL {
 			p.consume(lexer.COMMA, "Parameters must be separated by ','.")
 		}
@@ -289,7 +376,7 @@ func (p *parser) funcDecl() ast.Statement {
 
 	block := p.block()
 
-	return ast.FuncDecl{name, parameters, block}
+	return ast.FuncDecl{Name: name, Parameters: parameters, Defaults: defaults, Rest: rest, Block: block}
 }
 
 func (p *parser) varDecl() ast.Statement {
@@ -303,7 +390,7 @@ func (p *parser) varDecl() ast.Statement {
 	}
 
 	p.consumeMatch([]lexer.TokenType{lexer.NEWLINE, lexer.SEMICOLON}, "Expect ';' or new line after variable declaration.")
-	return ast.VarDecl{name, initializer}
+	return ast.VarDecl{Name: name, Initializer: initializer}
 }
 
 func (p *parser) statement() ast.Statement {
@@ -329,6 +416,15 @@ func (p *parser) statement() ast.Statement {
 	case lexer.RETURN:
 		p.advance()
 		return p.returnStmt()
+	case lexer.TRY:
+		p.advance()
+		return p.tryStmt()
+	case lexer.THROW:
+		p.advance()
+		return p.throwStmt()
+	case lexer.IMPORT:
+		p.advance()
+		return p.importStmt()
 	}
 
 	return p.exprStmt()
@@ -342,7 +438,7 @@ func (p *parser) exprStmt() ast.Statement {
 	p.consumeMatch([]lexer.TokenType{lexer.NEWLINE, lexer.SEMICOLON}, "Expect ';' or new line after expression.")
 	//}
 
-	return ast.ExprStmt{expr}
+	return ast.ExprStmt{Expr: expr}
 }
 
 func (p *parser) ifStmt() ast.Statement {
@@ -357,7 +453,7 @@ func (p *parser) ifStmt() ast.Statement {
 		elseBranch = p.statement()
 	}
 
-	return ast.IfStmt{condition, thenBranch, elseBranch}
+	return ast.IfStmt{Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}
 }
 
 func (p *parser) whileStmt() ast.Statement {
@@ -366,11 +462,11 @@ func (p *parser) whileStmt() ast.Statement {
 
 	loopBranch := p.statement()
 
-	return ast.WhileStmt{condition, loopBranch}
+	return ast.WhileStmt{Condition: condition, LoopBranch: loopBranch}
 }
 
-// For loops are syntactic sugar, they are expressed as while loops.
 func (p *parser) forStmt() ast.Statement {
+	keyword := p.previous()
 	declaration := p.declaration()
 
 	condition := p.equality()
@@ -381,17 +477,7 @@ func (p *parser) forStmt() ast.Statement {
 
 	loopBranch := p.statement()
 
-	// Add the increment to the end of the loopBranch (and make it into a block if it's not already)
-	loopBlock, ok := loopBranch.(ast.Block)
-	if ok {
-		loopBlock = ast.Block{append(loopBlock.Stmts, increment)}
-	} else if !ok {
-		loopBlock = ast.Block{[]ast.Statement{loopBlock, increment}}
-	}
-
-	forLoop := []ast.Statement{declaration, ast.WhileStmt{condition, loopBlock}}
-
-	return ast.Block{forLoop}
+	return ast.ForStmt{Keyword: keyword, Initializer: declaration, Condition: condition, Increment: increment, LoopBranch: loopBranch}
 }
 
 func (p *parser) returnStmt() ast.Statement {
@@ -404,7 +490,62 @@ func (p *parser) returnStmt() ast.Statement {
 
 	p.consume(lexer.NEWLINE, "Return statement must end in a new line.")
 
-	return ast.ReturnStmt{keyword, expr}
+	return ast.ReturnStmt{Keyword: keyword, Value: expr}
+}
+
+func (p *parser) tryStmt() ast.Statement {
+	keyword := p.previous()
+	p.consume(lexer.THEN, "Expect 'then' after try.")
+
+	tryBranch := p.statement()
+
+	var catchName lexer.Token
+	var catchBranch ast.Statement = nil
+	if p.match([]lexer.TokenType{lexer.CATCH}) {
+		// The error variable is optional, ex: 'catch then' ignores the error.
+		if p.match([]lexer.TokenType{lexer.IDENTIFIER}) {
+			catchName = p.previous()
+		}
+		p.consume(lexer.THEN, "Expect 'then' after catch.")
+		catchBranch = p.statement()
+	}
+
+	var finallyBranch ast.Statement = nil
+	if p.match([]lexer.TokenType{lexer.FINALLY}) {
+		p.consume(lexer.THEN, "Expect 'then' after finally.")
+		finallyBranch = p.statement()
+	}
+
+	if catchBranch == nil && finallyBranch == nil {
+		p.parseError(p.peek(), "Expect 'catch' or 'finally' after try block.")
+	}
+
+	return ast.TryStmt{Keyword: keyword, TryBranch: tryBranch, CatchName: catchName, CatchBranch: catchBranch, FinallyBranch: finallyBranch}
+}
+
+func (p *parser) throwStmt() ast.Statement {
+	keyword := p.previous()
+	value := p.expression()
+
+	p.consume(lexer.NEWLINE, "Throw statement must end in a new line.")
+
+	return ast.ThrowStmt{Keyword: keyword, Value: value}
+}
+
+func (p *parser) importStmt() ast.Statement {
+	keyword := p.previous()
+	p.consume(lexer.STRING, "Expect module path string after import.")
+	path := p.previous()
+
+	var name lexer.Token
+	if p.match([]lexer.TokenType{lexer.AS}) {
+		p.consume(lexer.IDENTIFIER, "Expect module name after 'as'.")
+		name = p.previous()
+	}
+
+	p.consumeMatch([]lexer.TokenType{lexer.NEWLINE, lexer.SEMICOLON}, "Expect ';' or new line after import.")
+
+	return ast.ImportStmt{Keyword: keyword, Path: path, Name: name}
 }
 
 func (p *parser) block() ast.Block {
@@ -417,7 +558,7 @@ func (p *parser) block() ast.Block {
 		p.consume(lexer.DEDENT, "Expect dedent after block statement.")
 	}
 
-	return ast.Block{stmts}
+	return ast.Block{Stmts: stmts}
 }
 
 // Error handling:
@@ -439,11 +580,17 @@ func (p *parser) consumeMatch(types []lexer.TokenType, message string) {
 }
 
 func (p *parser) parseError(token lexer.Token, message string) {
-	p.errFlag = true
-	errors.ThrowError(token.Line, message)
+	p.report(token, message)
 	p.synchronize()
 }
 
+// Reports an error in code that otherwise parses, so parsing carries on without synchronizing.
+func (p *parser) report(token lexer.Token, message string) {
+	p.errFlag = true
+	errors.ThrowError(p.Errors, token.Line, message)
+	p.SyntaxErrors = append(p.SyntaxErrors, errors.SyntaxError{Line: token.Line, Column: token.Column, Message: message})
+}
+
 // TODO: Synchronize to previous statement when a parseError is called.
 func (p *parser) synchronize() {
 	p.advance()
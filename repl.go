@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"friston/errors"
+	"friston/interpreter"
+	"friston/lexer"
+	"friston/lineedit"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	prompt             = ">>> "
+	continuationPrompt = "... "
+)
+
+// File in the home directory that REPL history is kept in.
+const historyFile = ".friston_history"
+
+// Reads statements from stdin and runs them in one interpreter, printing the value of expressions.
+// Input that starts a block, or leaves a bracket or string open, continues on the next line until it's complete.
+// Blocks are ended by a blank line.
+// Lines starting with ':' are commands (see :help).
+func repl() {
+	fmt.Printf("Entering REPL, type :help for commands:\n")
+
+	s := newSession()
+
+	editor := lineedit.NewEditor(os.Stdin, stdin, os.Stdout)
+	editor.Complete = s.complete
+	if home, err := os.UserHomeDir(); err == nil {
+		editor.UseHistoryFile(filepath.Join(home, historyFile))
+	}
+
+	var chunk []string
+	for {
+		p := prompt
+		if len(chunk) > 0 {
+			p = continuationPrompt
+		}
+
+		line, err := editor.ReadLine(p)
+		if err == lineedit.ErrInterrupted {
+			// Ctrl-C drops the current chunk.
+			chunk = nil
+			continue
+		} else if err != nil {
+			fmt.Println()
+			return
+		}
+
+		if len(chunk) == 0 && line == "exit" {
+			os.Exit(0)
+		}
+		if len(chunk) == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			s.command(strings.TrimSpace(line))
+			continue
+		}
+
+		// A blank line runs the chunk as it is, even if it's incomplete, so a mistake can't trap the prompt.
+		if strings.TrimSpace(line) != "" || len(chunk) > 0 {
+			chunk = append(chunk, line)
+		}
+		if strings.TrimSpace(line) != "" && incomplete(strings.Join(chunk, "\n")) {
+			continue
+		}
+
+		s.run(strings.Join(chunk, "\n"))
+		chunk = nil
+	}
+}
+
+// A REPL's interpreter, and the names of the globals it started with (natives and constants), which :env leaves out.
+type session struct {
+	inter    interpreter.Interpreter
+	builtins map[string]bool
+}
+
+func newSession() *session {
+	inter := newInterpreter(true, nil)
+	inter.Script = "<repl>"
+
+	s := session{inter: inter, builtins: map[string]bool{}}
+	for _, name := range inter.Globals() {
+		s.builtins[name] = true
+	}
+	return &s
+}
+
+// Completes keywords, natives and global variables (which the interpreter's globals include).
+func (s *session) complete(word string) []string {
+	names := append(lexer.Keywords(), s.inter.Globals()...)
+	for name := range interpreter.Natives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var completions []string
+	for n, name := range names {
+		if strings.HasPrefix(name, word) && (n == 0 || name != names[n-1]) {
+			completions = append(completions, name)
+		}
+	}
+	return completions
+}
+
+// Lexes, parses and runs source in the REPL's interpreter.
+func (s *session) run(src string) {
+	if stmts, ok := parse(src, true, os.Stderr); ok {
+		if exit, ok := s.inter.Interpret(stmts).(errors.ExitError); ok {
+			os.Exit(exit.Code)
+		}
+	}
+}
+
+// Checks whether src needs more lines: it ends with '=', 'then' or '~', has an indented block
+// (which lasts until a blank line), or has an open bracket or string.
+func incomplete(src string) bool {
+	if unterminatedString(src) || strings.HasSuffix(strings.TrimSpace(src), "~") {
+		return true
+	}
+
+	lex := lexer.NewLexer(src, true)
+	lex.Errors = ioutil.Discard
+	tokens, _ := lex.ScanTokens()
+
+	depth := 0
+	last := lexer.EOF
+	for _, tok := range tokens {
+		switch tok.TType {
+		case lexer.INDENT:
+			return true
+		case lexer.LEFT_PAREN, lexer.LEFT_BRACKET, lexer.LEFT_BRACE:
+			depth++
+		case lexer.RIGHT_PAREN, lexer.RIGHT_BRACKET, lexer.RIGHT_BRACE:
+			depth--
+		}
+
+		if tok.TType != lexer.NEWLINE && tok.TType != lexer.EOF {
+			last = tok.TType
+		}
+	}
+
+	return depth > 0 || last == lexer.EQUAL || last == lexer.THEN
+}
+
+// Strings can span lines, so a line ending inside one continues on the next. Quotes in comments are ignored.
+func unterminatedString(src string) bool {
+	inString := false
+	for n := 0; n < len(src); n++ {
+		if src[n] == '"' {
+			inString = !inString
+		} else if !inString && strings.HasPrefix(src[n:], "//") {
+			for n < len(src) && src[n] != '\n' {
+				n++
+			}
+		}
+	}
+	return inString
+}
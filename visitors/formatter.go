@@ -0,0 +1,496 @@
+package visitors
+
+import (
+	"friston/ast"
+	"friston/lexer"
+	"friston/parser"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Formats friston source in the canonical style: four space indents, spaces around binary operators and after
+// commas, and a blank line around function declarations. Comments are kept in place, and so are single blank
+// lines between statements. Syntax errors are reported to errs, and return false.
+func Format(src string, errs io.Writer) (string, bool) {
+	lex := lexer.NewLexer(src, false)
+	lex.Errors = errs
+	lex.Comments = true
+	tokens, lexErr := lex.ScanTokens()
+	if lexErr {
+		return "", false
+	}
+
+	// The parser only takes the code, and the comments are put back in between its statements.
+	var code, comments []lexer.Token
+	for _, tok := range tokens {
+		if tok.TType == lexer.COMMENT {
+			comments = append(comments, tok)
+		} else {
+			code = append(code, tok)
+		}
+	}
+
+	par := parser.NewParser(code)
+	par.Errors = errs
+	stmts, parErr := par.Parse()
+	if parErr {
+		return "", false
+	}
+
+	f := newFormatter(src, code, comments)
+	for _, stmt := range stmts {
+		f.statement(stmt)
+	}
+	f.flushComments(-1)
+
+	// The lexer skips a shebang line, so it's kept as it was.
+	out := strings.TrimLeft(f.out.String(), "\n")
+	if strings.HasPrefix(src, "#!") {
+		shebang := strings.TrimRight(strings.SplitN(src, "\n", 2)[0], "\r")
+		if f.blank[2] && out != "" {
+			shebang += "\n"
+		}
+		out = shebang + "\n" + out
+	}
+	return out, true
+}
+
+// A visitor that prints statements as formatted source. Expression visits return their source as a string.
+type Formatter struct {
+	out   strings.Builder
+	depth int
+	// Comments that haven't been printed yet, in order.
+	comments []lexer.Token
+	// Comments that follow code on the same line.
+	trailing map[lexer.Token]bool
+	// Source lines that are blank.
+	blank map[int]bool
+	// The indentation (in columns) of each line with code, and those lines in order.
+	indents   map[int]int
+	codeLines []int
+	// Whether the next statement is the first in its block, and whether the last one was a function declaration.
+	blockStart bool
+	afterFunc  bool
+	// The closing bracket of each opening one.
+	closing map[lexer.Token]lexer.Token
+	// The else, catch and finally keywords that haven't been printed yet, in order.
+	keywords map[lexer.TokenType][]lexer.Token
+	// How many brackets split over lines the current expression is in, and the line the last one closed on.
+	nested    int
+	closeLine int
+}
+
+func newFormatter(src string, code []lexer.Token, comments []lexer.Token) *Formatter {
+	f := Formatter{comments: comments, trailing: map[lexer.Token]bool{}, blank: map[int]bool{}, indents: map[int]int{}, blockStart: true, closing: map[lexer.Token]lexer.Token{}, keywords: map[lexer.TokenType][]lexer.Token{}}
+
+	for n, line := range strings.Split(src, "\n") {
+		if strings.TrimSpace(line) == "" {
+			f.blank[n+1] = true
+		}
+	}
+
+	var open []lexer.Token
+	for _, tok := range code {
+		switch tok.TType {
+		case lexer.NEWLINE, lexer.INDENT, lexer.DEDENT, lexer.EOF:
+			continue
+		case lexer.ELSE, lexer.CATCH, lexer.FINALLY:
+			f.keywords[tok.TType] = append(f.keywords[tok.TType], tok)
+		case lexer.LEFT_PAREN, lexer.LEFT_BRACKET, lexer.LEFT_BRACE:
+			open = append(open, tok)
+		case lexer.RIGHT_PAREN, lexer.RIGHT_BRACKET, lexer.RIGHT_BRACE:
+			if len(open) > 0 {
+				f.closing[open[len(open)-1]] = tok
+				open = open[:len(open)-1]
+			}
+		}
+		if _, ok := f.indents[tok.Line]; !ok {
+			f.indents[tok.Line] = tok.Column - 1
+			f.codeLines = append(f.codeLines, tok.Line)
+		}
+	}
+
+	for _, comment := range comments {
+		if indent, ok := f.indents[comment.Line]; ok && indent < comment.Column-1 {
+			f.trailing[comment] = true
+		}
+	}
+	return &f
+}
+
+func (f *Formatter) write(s string) {
+	f.out.WriteString(s)
+}
+
+func (f *Formatter) indent() {
+	f.write(strings.Repeat("    ", f.depth))
+}
+
+// Ends the line of a statement that started on line, adding the comment that followed it. A statement with
+// brackets split over lines ends on the line of the last one.
+func (f *Formatter) endLine(line int) {
+	if f.closeLine > line {
+		line = f.closeLine
+	}
+	f.closeLine = 0
+
+	if len(f.comments) > 0 && f.trailing[f.comments[0]] && f.comments[0].Line == line {
+		f.write(" " + f.comments[0].Lexeme)
+		f.comments = f.comments[1:]
+	}
+	f.write("\n")
+}
+
+// Prints the comments before line (or all of them, if line is -1) on their own lines, keeping blank lines before them.
+func (f *Formatter) flushComments(line int) {
+	for len(f.comments) > 0 && (line == -1 || f.comments[0].Line < line) {
+		comment := f.comments[0]
+		f.comments = f.comments[1:]
+
+		f.blankLine(comment.Line, false)
+		f.indent()
+		f.write(comment.Lexeme + "\n")
+		f.blockStart = false
+	}
+}
+
+// Keeps a blank line before line, unless it's the start of a block. Function declarations always have one.
+func (f *Formatter) blankLine(line int, function bool) {
+	if !f.blockStart && (f.blank[line-1] || function || f.afterFunc) {
+		f.write("\n")
+	}
+	f.afterFunc = false
+}
+
+func (f *Formatter) statement(stmt ast.Statement) {
+	line := ast.Line(stmt)
+	_, function := stmt.(ast.FuncDecl)
+
+	// A blank line around a function goes before any comments above it.
+	if function && len(f.comments) > 0 && f.comments[0].Line < line {
+		f.blankLine(f.comments[0].Line, true)
+		f.blockStart = true
+		f.flushComments(line)
+		f.blankLine(line, false)
+	} else {
+		f.flushComments(line)
+		f.blankLine(line, function)
+	}
+
+	if block, ok := stmt.(ast.Block); ok {
+		f.block(block)
+	} else {
+		f.indent()
+		stmt.Accept(f)
+	}
+
+	f.blockStart = false
+	f.afterFunc = function
+}
+
+// Prints the statements of a block one indent deeper, and the comments at the end of it.
+func (f *Formatter) block(block ast.Block) {
+	f.depth++
+	f.blockStart = true
+	for _, stmt := range block.Stmts {
+		f.statement(stmt)
+	}
+
+	// Comments after the last statement belong to the block if they're indented as deep as it,
+	// and come before the next line of code outside of it.
+	if len(block.Stmts) > 0 {
+		end := f.blockEnd(ast.Line(block.Stmts[len(block.Stmts)-1]))
+		for len(f.comments) > 0 {
+			comment := f.comments[0]
+			if f.trailing[comment] || comment.Line >= end || comment.Column-1 < f.depth*4 {
+				break
+			}
+			f.flushComments(comment.Line + 1)
+		}
+	}
+	f.depth--
+}
+
+// Returns the first line of code after line that is outside of the current block.
+func (f *Formatter) blockEnd(line int) int {
+	n := sort.SearchInts(f.codeLines, line+1)
+	for ; n < len(f.codeLines); n++ {
+		if f.indents[f.codeLines[n]] < f.depth*4 {
+			return f.codeLines[n]
+		}
+	}
+	return int(^uint(0) >> 1)
+}
+
+// Prints the branch of an if, loop, try or function after its header. Blocks go on the lines after it,
+// and other statements on the same line, ex: if x then println(x)
+func (f *Formatter) branch(stmt ast.Statement, line int) {
+	if block, ok := stmt.(ast.Block); ok {
+		f.endLine(line)
+		f.block(block)
+	} else {
+		f.write(" ")
+		stmt.Accept(f)
+	}
+}
+
+// Starts the line of an else, catch or finally keyword, after the comments above it, and returns its line.
+// Each statement takes its keywords in order, so those of statements nested in its first branch are taken first.
+func (f *Formatter) keyword(tt lexer.TokenType) int {
+	tok := f.keywords[tt][0]
+	f.keywords[tt] = f.keywords[tt][1:]
+
+	f.flushComments(tok.Line)
+	f.indent()
+	return tok.Line
+}
+
+func (f *Formatter) expr(expr ast.Expression) string {
+	return expr.Accept(f).(string)
+}
+
+// An element of a list or call, and a function that formats it, so the comments before it are taken first.
+type element struct {
+	start  lexer.Token
+	format func() string
+}
+
+func (f *Formatter) elements(exprs []ast.Expression) []element {
+	var elements []element
+	for _, expr := range exprs {
+		expr := expr
+		elements = append(elements, element{ast.Start(expr), func() string { return f.expr(expr) }})
+	}
+	return elements
+}
+
+// Formats the elements between an opening bracket and its closing one, followed by closer. They're separated
+// by commas on one line, unless there are comments between the brackets, when each element goes on its own
+// line and the comments stay where they were.
+func (f *Formatter) bracketed(open lexer.Token, elements []element, closer string) string {
+	close := f.closing[open]
+	if len(f.comments) == 0 || !before(f.comments[0], close) {
+		var parts []string
+		for _, e := range elements {
+			parts = append(parts, e.format())
+		}
+		return strings.Join(parts, ", ") + closer
+	}
+
+	f.nested++
+	indent := strings.Repeat("    ", f.depth+f.nested)
+	var out strings.Builder
+	for n, e := range elements {
+		out.WriteString(f.commentsBefore(e.start, indent))
+		out.WriteString("\n" + indent + e.format())
+		if n < len(elements)-1 {
+			out.WriteString(",")
+		}
+	}
+	out.WriteString(f.commentsBefore(close, indent))
+	f.nested--
+
+	f.closeLine = close.Line
+	return out.String() + "\n" + strings.Repeat("    ", f.depth+f.nested) + closer
+}
+
+// Takes the comments before tok. Comments that followed code stay at the end of the line, and others go on
+// their own lines.
+func (f *Formatter) commentsBefore(tok lexer.Token, indent string) string {
+	var out string
+	for len(f.comments) > 0 && before(f.comments[0], tok) {
+		if f.trailing[f.comments[0]] {
+			out += " " + f.comments[0].Lexeme
+		} else {
+			out += "\n" + indent + f.comments[0].Lexeme
+		}
+		f.comments = f.comments[1:]
+	}
+	return out
+}
+
+func before(a lexer.Token, b lexer.Token) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// Expressions:
+
+func (f *Formatter) VisitBinary(b ast.Binary) interface{} {
+	return f.expr(b.X) + " " + b.Op.Lexeme + " " + f.expr(b.Y)
+}
+
+func (f *Formatter) VisitLogic(l ast.Logic) interface{} {
+	return f.expr(l.X) + " " + l.Op.Lexeme + " " + f.expr(l.Y)
+}
+
+func (f *Formatter) VisitUnary(u ast.Unary) interface{} {
+	x := f.expr(u.X)
+	// Two minuses would be read as a decrement.
+	if u.Op.Lexeme == "-" && strings.HasPrefix(x, "-") {
+		return "- " + x
+	}
+	return u.Op.Lexeme + x
+}
+
+func (f *Formatter) VisitGroup(g ast.Group) interface{} {
+	return "(" + f.expr(g.X) + ")"
+}
+
+func (f *Formatter) VisitLiteral(l ast.Literal) interface{} {
+	return l.X.Lexeme
+}
+
+func (f *Formatter) VisitVariable(vr ast.Variable) interface{} {
+	return vr.Name.Lexeme
+}
+
+func (f *Formatter) VisitAssignment(a ast.Assignment) interface{} {
+	if a.Postfix.Lexeme != "" {
+		return a.Name.Lexeme + a.Postfix.Lexeme
+	}
+	return a.Name.Lexeme + " = " + f.expr(a.Value)
+}
+
+func (f *Formatter) VisitCall(c ast.Call) interface{} {
+	callee := f.expr(c.Callee)
+	args := f.elements(c.Arguments)
+	for _, keyword := range c.Keywords {
+		keyword := keyword
+		args = append(args, element{keyword.Name, func() string { return keyword.Name.Lexeme + ": " + f.expr(keyword.Value) }})
+	}
+	return callee + "(" + f.bracketed(c.Paren, args, ")")
+}
+
+func (f *Formatter) VisitGet(g ast.Get) interface{} {
+	return f.expr(g.Object) + "." + g.Name.Lexeme
+}
+
+func (f *Formatter) VisitIndex(ix ast.Index) interface{} {
+	return f.expr(ix.Object) + "[" + f.expr(ix.Index) + "]"
+}
+
+func (f *Formatter) VisitListLiteral(l ast.ListLiteral) interface{} {
+	return "[" + f.bracketed(l.Bracket, f.elements(l.Elements), "]")
+}
+
+// Statements, which are printed from the current indent:
+
+func (f *Formatter) VisitExprStmt(e ast.ExprStmt) interface{} {
+	f.write(f.expr(e.Expr))
+	f.endLine(ast.Line(e))
+	return nil
+}
+
+func (f *Formatter) VisitIfStmt(stmt ast.IfStmt) interface{} {
+	f.write("if " + f.expr(stmt.Condition) + " then")
+	f.branch(stmt.ThenBranch, ast.Line(stmt))
+
+	if stmt.ElseBranch != nil {
+		line := f.keyword(lexer.ELSE)
+		f.write("else then")
+		f.branch(stmt.ElseBranch, line)
+	}
+	return nil
+}
+
+func (f *Formatter) VisitWhileStmt(stmt ast.WhileStmt) interface{} {
+	f.write("while " + f.expr(stmt.Condition) + " then")
+	f.branch(stmt.LoopBranch, ast.Line(stmt))
+	return nil
+}
+
+func (f *Formatter) VisitForStmt(stmt ast.ForStmt) interface{} {
+	initializer := ""
+	if decl, ok := stmt.Initializer.(ast.VarDecl); ok {
+		initializer = f.varDecl(decl)
+	}
+
+	f.write("for " + initializer + "; " + f.expr(stmt.Condition) + "; " + f.expr(stmt.Increment) + " then")
+	f.branch(stmt.LoopBranch, ast.Line(stmt))
+	return nil
+}
+
+func (f *Formatter) VisitFuncDecl(decl ast.FuncDecl) interface{} {
+	var params []string
+	for n, param := range decl.Parameters {
+		if decl.Rest && n == len(decl.Parameters)-1 {
+			params = append(params, "..."+param.Lexeme)
+		} else if n < len(decl.Defaults) && decl.Defaults[n] != nil {
+			params = append(params, param.Lexeme+" = "+f.expr(decl.Defaults[n]))
+		} else {
+			params = append(params, param.Lexeme)
+		}
+	}
+
+	header := "function " + decl.Name.Lexeme + ": " + strings.Join(params, ", ") + " ="
+	f.write(strings.Replace(header, ":  =", ": =", 1))
+	f.branch(decl.Block, decl.Name.Line)
+	return nil
+}
+
+func (f *Formatter) varDecl(d ast.VarDecl) string {
+	if d.Initializer == nil {
+		return "let " + d.Name.Lexeme
+	}
+	return "let " + d.Name.Lexeme + " = " + f.expr(d.Initializer)
+}
+
+func (f *Formatter) VisitVarDecl(d ast.VarDecl) interface{} {
+	f.write(f.varDecl(d))
+	f.endLine(d.Name.Line)
+	return nil
+}
+
+func (f *Formatter) VisitReturn(r ast.ReturnStmt) interface{} {
+	if r.Value == nil {
+		f.write("return")
+	} else {
+		f.write("return " + f.expr(r.Value))
+	}
+	f.endLine(r.Keyword.Line)
+	return nil
+}
+
+func (f *Formatter) VisitTryStmt(stmt ast.TryStmt) interface{} {
+	f.write("try then")
+	f.branch(stmt.TryBranch, stmt.Keyword.Line)
+
+	if stmt.CatchBranch != nil {
+		line := f.keyword(lexer.CATCH)
+		if stmt.CatchName.Lexeme != "" {
+			f.write("catch " + stmt.CatchName.Lexeme + " then")
+		} else {
+			f.write("catch then")
+		}
+		f.branch(stmt.CatchBranch, line)
+	}
+
+	if stmt.FinallyBranch != nil {
+		line := f.keyword(lexer.FINALLY)
+		f.write("finally then")
+		f.branch(stmt.FinallyBranch, line)
+	}
+	return nil
+}
+
+func (f *Formatter) VisitThrowStmt(stmt ast.ThrowStmt) interface{} {
+	f.write("throw " + f.expr(stmt.Value))
+	f.endLine(stmt.Keyword.Line)
+	return nil
+}
+
+func (f *Formatter) VisitImportStmt(stmt ast.ImportStmt) interface{} {
+	f.write("import " + stmt.Path.Lexeme)
+	if stmt.Name.Lexeme != "" {
+		f.write(" as " + stmt.Name.Lexeme)
+	}
+	f.endLine(stmt.Keyword.Line)
+	return nil
+}
+
+// A block without a header, which is indented further than the statement before it.
+func (f *Formatter) VisitBlock(b ast.Block) interface{} {
+	f.block(b)
+	return nil
+}
@@ -0,0 +1,74 @@
+package visitors
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "spacing",
+			src:  "let x=1+2\nprintln( x )\n",
+			want: "let x = 1 + 2\nprintln(x)\n",
+		},
+		{
+			name: "brackets without comments are joined",
+			src:  "let xs = [\n    1,\n    2\n]\n",
+			want: "let xs = [1, 2]\n",
+		},
+		{
+			name: "comments inside brackets",
+			src: "let xs = [\n    1, // one\n    // two next\n    2,\n    [3, // three\n        4]\n] // end\n" +
+				"println(xs,   // first\n    \"b\")\n",
+			want: "let xs = [\n    1, // one\n    // two next\n    2,\n    [\n        3, // three\n        4\n    ]\n] // end\n" +
+				"println(\n    xs, // first\n    \"b\"\n)\n",
+		},
+		{
+			name: "comments inside brackets in a block",
+			src:  "function f: n =\n    return [\n        n, // n\n        2\n    ]\n",
+			want: "function f: n =\n    return [\n        n, // n\n        2\n    ]\n",
+		},
+		{
+			name: "shebang",
+			src:  "#!/usr/bin/env friston\n\nlet x=1\n",
+			want: "#!/usr/bin/env friston\n\nlet x = 1\n",
+		},
+		{
+			name: "comment before else",
+			src:  "if x then\n    println(1)\n// otherwise\nelse then // else\n    println(2)\n",
+			want: "if x then\n    println(1)\n// otherwise\nelse then // else\n    println(2)\n",
+		},
+		{
+			name: "comments before catch and finally",
+			src:  "try then\n    f()\n    // in try\n// caught\ncatch e then\n    println(e)\n// always\nfinally then\n    g()\n",
+			want: "try then\n    f()\n    // in try\n// caught\ncatch e then\n    println(e)\n// always\nfinally then\n    g()\n",
+		},
+		{
+			name: "nested else",
+			src:  "if a then\n    if b then\n        f()\n    // inner\n    else then\n        g()\n// outer\nelse then\n    h()\n",
+			want: "if a then\n    if b then\n        f()\n    // inner\n    else then\n        g()\n// outer\nelse then\n    h()\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := Format(test.src, ioutil.Discard)
+			if !ok {
+				t.Fatalf("Format(%q) failed", test.src)
+			}
+			if got != test.want {
+				t.Fatalf("Format(%q) = %q, want %q", test.src, got, test.want)
+			}
+
+			again, ok := Format(got, ioutil.Discard)
+			if !ok || again != got {
+				t.Fatalf("formatting again = %q, want %q", again, got)
+			}
+		})
+	}
+}
@@ -60,11 +60,41 @@ func (printer ASTPrinter) VisitCall(c ast.Call) interface{} {
 	for _, arg := range c.Arguments {
 		arg.Accept(printer)
 	}
+	for _, keyword := range c.Keywords {
+		fmt.Printf(" %s: ", keyword.Name.Lexeme)
+		keyword.Value.Accept(printer)
+	}
 	fmt.Printf(") ")
 
 	return nil
 }
 
+func (printer ASTPrinter) VisitGet(g ast.Get) interface{} {
+	g.Object.Accept(printer)
+	fmt.Printf(".%s", g.Name.Lexeme)
+	return nil
+}
+
+func (printer ASTPrinter) VisitIndex(ix ast.Index) interface{} {
+	ix.Object.Accept(printer)
+	fmt.Printf("[")
+	ix.Index.Accept(printer)
+	fmt.Printf("]")
+	return nil
+}
+
+func (printer ASTPrinter) VisitListLiteral(l ast.ListLiteral) interface{} {
+	fmt.Printf("[")
+	for n, element := range l.Elements {
+		if n > 0 {
+			fmt.Printf(", ")
+		}
+		element.Accept(printer)
+	}
+	fmt.Printf("]")
+	return nil
+}
+
 func (printer ASTPrinter) VisitExprStmt(e ast.ExprStmt) interface{} {
 	e.Expr.Accept(printer)
 	fmt.Printf("; ")
@@ -94,10 +124,28 @@ func (printer ASTPrinter) VisitWhileStmt(stmt ast.WhileStmt) interface{} {
 	return nil
 }
 
+func (printer ASTPrinter) VisitForStmt(stmt ast.ForStmt) interface{} {
+	fmt.Printf("for (")
+	stmt.Initializer.Accept(printer)
+	stmt.Condition.Accept(printer)
+	fmt.Printf("; ")
+	stmt.Increment.Accept(printer)
+	fmt.Printf(") ")
+	stmt.LoopBranch.Accept(printer)
+	return nil
+}
+
 func (printer ASTPrinter) VisitFuncDecl(f ast.FuncDecl) interface{} {
 	fmt.Printf("\nfunction %s : ", f.Name.Lexeme)
-	for _, param := range f.Parameters {
+	for n, param := range f.Parameters {
+		if f.Rest && n == len(f.Parameters)-1 {
+			fmt.Printf(" ...")
+		}
 		fmt.Printf(" %s ", param.Lexeme)
+		if f.Defaults[n] != nil {
+			fmt.Printf("= ")
+			f.Defaults[n].Accept(printer)
+		}
 	}
 	fmt.Printf(" =\n")
 	f.Block.Accept(printer)
@@ -118,6 +166,38 @@ func (printer ASTPrinter) VisitReturn(r ast.ReturnStmt) interface{} {
 	return nil
 }
 
+func (printer ASTPrinter) VisitTryStmt(stmt ast.TryStmt) interface{} {
+	fmt.Printf("try ")
+	stmt.TryBranch.Accept(printer)
+
+	if stmt.CatchBranch != nil {
+		fmt.Printf("catch %s ", stmt.CatchName.Lexeme)
+		stmt.CatchBranch.Accept(printer)
+	}
+
+	if stmt.FinallyBranch != nil {
+		fmt.Printf("finally ")
+		stmt.FinallyBranch.Accept(printer)
+	}
+	return nil
+}
+
+func (printer ASTPrinter) VisitThrowStmt(stmt ast.ThrowStmt) interface{} {
+	fmt.Printf("throw ")
+	stmt.Value.Accept(printer)
+	fmt.Printf("; ")
+	return nil
+}
+
+func (printer ASTPrinter) VisitImportStmt(stmt ast.ImportStmt) interface{} {
+	fmt.Printf("import %q ", stmt.Path.Literal)
+	if stmt.Name.Lexeme != "" {
+		fmt.Printf("as %s ", stmt.Name.Lexeme)
+	}
+	fmt.Printf("; ")
+	return nil
+}
+
 func (printer ASTPrinter) VisitBlock(b ast.Block) interface{} {
 	fmt.Printf(" { ")
 	for _, s := range b.Stmts {